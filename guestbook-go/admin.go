@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// CodeUnauthorized is reported when admin credentials are missing or wrong.
+const CodeUnauthorized = "unauthorized"
+
+// redactedValue replaces the value of environment variables that match one
+// of the redaction patterns.
+const redactedValue = "[redacted]"
+
+// adminAuth protects the admin routes with the configured bearer token or
+// basic auth credentials. With neither configured the routes are open to
+// anyone who can reach the server.
+func (c *Config) adminAuth(next http.Handler) http.Handler {
+	if !c.adminCredentials() {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if c.AdminToken != "" {
+			if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok && secretEqual(token, c.AdminToken) {
+				next.ServeHTTP(rw, req)
+				return
+			}
+		}
+		if c.AdminUser != "" {
+			if user, password, ok := req.BasicAuth(); ok && secretEqual(user, c.AdminUser) && secretEqual(password, c.AdminPassword) {
+				next.ServeHTTP(rw, req)
+				return
+			}
+			rw.Header().Set("WWW-Authenticate", `Basic realm="guestbook admin"`)
+		} else {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="guestbook admin"`)
+		}
+		writeError(rw, req, &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "admin credentials required"})
+	})
+}
+
+// adminCredentials reports whether a bearer token or basic auth credentials
+// are configured for the admin routes.
+func (c *Config) adminCredentials() bool {
+	return c.AdminToken != "" || c.AdminUser != ""
+}
+
+// checkAdminCredentials rejects basic auth credentials that are only half
+// configured. A user without a password would let anyone in who knows the
+// user name, and a password without a user would be ignored.
+func (c *Config) checkAdminCredentials() error {
+	if c.AdminUser != "" && c.AdminPassword == "" {
+		return errors.New("-admin-user needs an -admin-password")
+	}
+	if c.AdminPassword != "" && c.AdminUser == "" {
+		return errors.New("-admin-password needs an -admin-user")
+	}
+	return nil
+}
+
+func secretEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+// redactPatterns returns the upper cased glob patterns of the environment
+// variable names whose values /env must not show.
+func (c *Config) redactPatterns() []string {
+	patterns := splitList(c.EnvRedact)
+	for i, pattern := range patterns {
+		patterns[i] = strings.ToUpper(pattern)
+	}
+	return patterns
+}
+
+// redacted reports whether name matches one of the patterns, ignoring case.
+func redacted(name string, patterns []string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// newEnvHandler returns a handler that lists the environment of the server,
+// hiding the values of variables matching one of the redact patterns.
+func newEnvHandler(redact []string) appHandler {
+	return func(rw http.ResponseWriter, req *http.Request) error {
+		environment := make(map[string]string)
+		for _, item := range os.Environ() {
+			key, val, _ := strings.Cut(item, "=")
+			if redacted(key, redact) {
+				val = redactedValue
+			}
+			environment[key] = val
+		}
+		return writeJSON(rw, http.StatusOK, environment)
+	}
+}
@@ -0,0 +1,453 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Backup formats.
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// ActionImport is recorded in the audit log for every list an import
+// replaces.
+const ActionImport = "import"
+
+// maxImportBytes bounds the size of a backup posted to the import endpoint.
+const maxImportBytes = 64 << 20
+
+// maxBackupLine bounds the length of a line of an NDJSON backup.
+const maxBackupLine = 1 << 20
+
+// backupColumns are the columns of a CSV backup.
+var backupColumns = []string{"key", "id", "value", "author", "createdAt", "clientHash", "status"}
+
+// BackupEntry is an entry in a backup, which is a line of an NDJSON backup
+// or a row of a CSV one. Entries held back from a list have their status.
+type BackupEntry struct {
+	Key        string    `json:"key"`
+	ID         string    `json:"id,omitempty"`
+	Value      string    `json:"value"`
+	Author     string    `json:"author,omitempty"`
+	CreatedAt  time.Time `json:"createdAt,omitzero"`
+	ClientHash string    `json:"clientHash,omitempty"`
+	Status     string    `json:"status,omitempty"`
+}
+
+func newBackupEntry(key string, entry Entry) BackupEntry {
+	return BackupEntry{
+		Key:        key,
+		ID:         entry.ID,
+		Value:      entry.Value,
+		Author:     entry.Author,
+		CreatedAt:  entry.CreatedAt,
+		ClientHash: entry.ClientHash,
+		Status:     entry.Status,
+	}
+}
+
+// entry validates b as new entries are, except that the value may be of
+// any length, and returns it as an Entry.
+func (b BackupEntry) entry() (Entry, error) {
+	if err := validateKey(b.Key); err != nil {
+		return Entry{}, err
+	}
+	if _, err := validateValue(b.Value, 0); err != nil {
+		return Entry{}, err
+	}
+	if b.Author != "" {
+		if _, err := validateText("author", b.Author, maxAuthorLength); err != nil {
+			return Entry{}, err
+		}
+	}
+	if b.Status != "" && b.Status != EntryHidden && b.Status != EntryPending {
+		return Entry{}, badRequest(CodeBadRequest, "status must be empty, %q or %q", EntryHidden, EntryPending)
+	}
+	return Entry{
+		ID:         b.ID,
+		Value:      b.Value,
+		Author:     b.Author,
+		CreatedAt:  b.CreatedAt,
+		ClientHash: b.ClientHash,
+		Status:     b.Status,
+	}, nil
+}
+
+// storedForm returns how Restore stores entry in a list. Entries exported
+// from bare values are stored as the bare value again, so that a restored
+// list is the same as the exported one.
+func storedForm(entry Entry) (string, error) {
+	if entry.ID == bareEntryID(entry.Value) && entry.CreatedAt.IsZero() && entry.Author == "" && entry.ClientHash == "" {
+		return entry.Value, nil
+	}
+	_, record, err := encodeEntry(entry)
+	return record, err
+}
+
+// backupList is a list read from a backup, with the entries held back from
+// it.
+type backupList struct {
+	Key     string
+	Entries []Entry
+	Held    []Entry
+}
+
+// ImportResult reports how many entries an import restored to a list, or
+// would restore in a dry run.
+type ImportResult struct {
+	Key     string `json:"key"`
+	Entries int    `json:"entries"`
+	Held    int    `json:"held"`
+}
+
+func checkFormat(format string) error {
+	if format != FormatNDJSON && format != FormatCSV {
+		return badRequest(CodeBadRequest, "format must be %q or %q", FormatNDJSON, FormatCSV)
+	}
+	return nil
+}
+
+// exportLists writes the entries of the lists, or of every guestbook if
+// keys is empty, to w in format. Every list is read before anything is
+// written, so that a failure does not leave a partial backup behind.
+func exportLists(ctx context.Context, s Store, w io.Writer, format string, keys []string) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		var err error
+		if keys, err = s.Guestbooks(ctx); err != nil {
+			return err
+		}
+	}
+	var backup []BackupEntry
+	for _, key := range keys {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		entries, _, err := s.Range(ctx, key, Page{})
+		if err != nil {
+			return err
+		}
+		held, err := s.Held(ctx, key)
+		if err != nil {
+			return err
+		}
+		for _, entry := range append(entries, held...) {
+			backup = append(backup, newBackupEntry(key, entry))
+		}
+	}
+
+	if format == FormatNDJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		for _, b := range backup {
+			if err := encoder.Encode(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(backupColumns)
+	for _, b := range backup {
+		var created string
+		if !b.CreatedAt.IsZero() {
+			created = b.CreatedAt.Format(time.RFC3339Nano)
+		}
+		writer.Write([]string{b.Key, b.ID, b.Value, b.Author, created, b.ClientHash, b.Status})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readBackup reads and validates a backup in format, and returns its lists
+// in the order they first appear.
+func readBackup(r io.Reader, format string) ([]*backupList, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	var lists []*backupList
+	byKey := make(map[string]*backupList)
+	add := func(line int, b BackupEntry) error {
+		entry, err := b.entry()
+		if err != nil {
+			return badRequest(CodeBadRequest, "line %d: %v", line, err)
+		}
+		list := byKey[b.Key]
+		if list == nil {
+			list = &backupList{Key: b.Key}
+			byKey[b.Key] = list
+			lists = append(lists, list)
+		}
+		if entry.Status == "" {
+			list.Entries = append(list.Entries, entry)
+		} else {
+			list.Held = append(list.Held, entry)
+		}
+		return nil
+	}
+
+	if format == FormatNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxBackupLine)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var b BackupEntry
+			if err := json.Unmarshal(scanner.Bytes(), &b); err != nil {
+				return nil, badRequest(CodeBadRequest, "line %d: %v", line, err)
+			}
+			if err := add(line, b); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, readFailed(err)
+		}
+		return lists, nil
+	}
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, readFailed(err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"key", "value"} {
+		if _, ok := columns[name]; !ok {
+			return nil, badRequest(CodeBadRequest, "line 1: missing the %q column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lists, nil
+		} else if err != nil {
+			return nil, readFailed(err)
+		}
+		line, _ := reader.FieldPos(0)
+		b := BackupEntry{
+			Key:        field(record, "key"),
+			ID:         field(record, "id"),
+			Value:      field(record, "value"),
+			Author:     field(record, "author"),
+			ClientHash: field(record, "clientHash"),
+			Status:     field(record, "status"),
+		}
+		if created := field(record, "createdAt"); created != "" {
+			if b.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+				return nil, badRequest(CodeBadRequest, "line %d: invalid createdAt %q", line, created)
+			}
+		}
+		if err := add(line, b); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readFailed reports a backup that could not be read, such as malformed CSV
+// or one larger than maxImportBytes.
+func readFailed(err error) *Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeTooLarge, Message: fmt.Sprintf("backup must be at most %d bytes", maxImportBytes)}
+	}
+	return badRequest(CodeBadRequest, "reading the backup failed: %v", err)
+}
+
+// importLists restores the lists of the backup in r to s, replacing each
+// of them as a whole. The backup is read and validated completely first, so
+// nothing is written unless all of it is valid, and with dryRun nothing is
+// written at all.
+func importLists(ctx context.Context, s Store, r io.Reader, format string, dryRun bool) ([]ImportResult, error) {
+	lists, err := readBackup(r, format)
+	if err != nil {
+		return nil, err
+	}
+	results := []ImportResult{}
+	for _, list := range lists {
+		if !dryRun {
+			if err := s.Restore(ctx, list.Key, list.Entries, list.Held); err != nil {
+				return results, err
+			}
+		}
+		results = append(results, ImportResult{Key: list.Key, Entries: len(list.Entries), Held: len(list.Held)})
+	}
+	return results, nil
+}
+
+// ExportHandler returns a backup of the lists named by the key query
+// parameters, or of every guestbook.
+func ExportHandler(rw http.ResponseWriter, req *http.Request) error {
+	query := req.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = FormatNDJSON
+	}
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := exportLists(req.Context(), store, &buf, format, query["key"]); err != nil {
+		return err
+	}
+	contentType := "application/x-ndjson"
+	if format == FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Disposition", `attachment; filename="guestbook-backup.`+format+`"`)
+	rw.WriteHeader(http.StatusOK)
+	_, err := rw.Write(buf.Bytes())
+	return err
+}
+
+// ImportResponse is the JSON body returned by the import endpoint.
+type ImportResponse struct {
+	DryRun bool           `json:"dryRun"`
+	Lists  []ImportResult `json:"lists"`
+}
+
+// ImportHandler restores the lists of the backup in the request body, in
+// the format query parameter. With dry_run=true it only reports what would
+// be restored.
+func ImportHandler(rw http.ResponseWriter, req *http.Request) error {
+	query := req.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = FormatNDJSON
+	}
+	dryRun := false
+	if v := query.Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			return badRequest(CodeBadRequest, "dry_run must be true or false")
+		}
+	}
+
+	body := http.MaxBytesReader(rw, req.Body, maxImportBytes)
+	results, err := importLists(req.Context(), store, body, format, dryRun)
+	if !dryRun {
+		for _, result := range results {
+			audit(req, ActionImport, result.Key, "")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(rw, http.StatusOK, ImportResponse{DryRun: dryRun, Lists: results})
+}
+
+// runCommand runs the export, import or migrate subcommand in args against
+// the store of config. Errors are fatal.
+func runCommand(config *Config, args []string) {
+	if args[0] == "migrate" {
+		runMigrate(config, args)
+		return
+	}
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	format := flags.String("format", FormatNDJSON, "Backup format, ndjson or csv.")
+	var output *string
+	var dryRun *bool
+	switch args[0] {
+	case "export":
+		output = flags.String("o", "", "File to write the backup to instead of standard output.")
+		flags.Usage = func() {
+			fmt.Fprintf(flags.Output(), "Usage: %s [flags] export [-format ndjson|csv] [-o file] [list...]\n", os.Args[0])
+			flags.PrintDefaults()
+		}
+	case "import":
+		dryRun = flags.Bool("dry-run", false, "Only check the backup and report what would be restored.")
+		flags.Usage = func() {
+			fmt.Fprintf(flags.Output(), "Usage: %s [flags] import [-format ndjson|csv] [-dry-run] [file]\n", os.Args[0])
+			flags.PrintDefaults()
+		}
+	default:
+		fatal("invalid command line", fmt.Errorf("unknown command %q, expected export, import or migrate", args[0]))
+	}
+	flags.Parse(args[1:])
+
+	s, err := config.NewStore()
+	if err != nil {
+		fatal("creating the store failed", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if output != nil {
+		w := os.Stdout
+		if *output != "" {
+			if w, err = os.Create(*output); err != nil {
+				fatal("creating the backup failed", err)
+			}
+		}
+		if err := exportLists(ctx, s, w, *format, flags.Args()); err != nil {
+			fatal("exporting failed", err)
+		}
+		if err := w.Close(); err != nil {
+			fatal("writing the backup failed", err)
+		}
+		return
+	}
+
+	r := os.Stdin
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	} else if flags.NArg() == 1 {
+		if r, err = os.Open(flags.Arg(0)); err != nil {
+			fatal("opening the backup failed", err)
+		}
+		defer r.Close()
+	}
+	results, err := importLists(ctx, s, r, *format, *dryRun)
+	for _, result := range results {
+		slog.Info("list imported", "key", result.Key, "entries", result.Entries, "held", result.Held, "dry_run", *dryRun)
+	}
+	if err != nil {
+		fatal("importing failed", err)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{AdminToken: "secret"})
+			auth := []string{"Authorization", "Bearer secret"}
+			admin := server.URL + "/api/v1/admin"
+
+			var hidden Entry
+			for i, post := range []struct{ key, body string }{
+				{"guestbook", `{"value": "hello", "author": "Ann"}`},
+				{"guestbook", `{"value": "hidden, with \"quotes\""}`},
+				{"party", `{"value": "party!"}`},
+			} {
+				resp := do(t, "POST", server.URL+"/api/v1/lists/"+post.key+"/entries", post.body)
+				expectStatus(t, resp, http.StatusCreated)
+				if i == 1 {
+					decode(t, resp, &hidden)
+				}
+			}
+			expectStatus(t, do(t, "POST", admin+"/lists/guestbook/entries/"+hidden.ID+"/hide", "", auth...), http.StatusOK)
+			original, _, err := s.Range(context.Background(), "guestbook", Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectError(t, do(t, "GET", admin+"/export", ""), http.StatusUnauthorized, CodeUnauthorized)
+			backups := make(map[string]string)
+			for _, format := range []string{FormatNDJSON, FormatCSV} {
+				resp := do(t, "GET", admin+"/export?format="+format, "", auth...)
+				expectStatus(t, resp, http.StatusOK)
+				body, _ := io.ReadAll(resp.Body)
+				backups[format] = string(body)
+			}
+			if lines := strings.Split(strings.TrimSpace(backups[FormatNDJSON]), "\n"); len(lines) != 3 {
+				t.Errorf("expected a line for each entry, got %q", lines)
+			}
+			if header, _, _ := strings.Cut(backups[FormatCSV], "\n"); header != strings.Join(backupColumns, ",") {
+				t.Errorf("expected the CSV header first, got %q", header)
+			}
+			resp := do(t, "GET", admin+"/export?key=party", "", auth...)
+			if body, _ := io.ReadAll(resp.Body); strings.Count(string(body), "\n") != 1 || !strings.Contains(string(body), "party!") {
+				t.Errorf("expected only the party list, got %s", body)
+			}
+			expectError(t, do(t, "GET", admin+"/export?format=xml", "", auth...), http.StatusBadRequest, CodeBadRequest)
+
+			for _, format := range []string{FormatNDJSON, FormatCSV} {
+				expectStatus(t, do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value": "after the backup"}`), http.StatusCreated)
+				expectStatus(t, do(t, "POST", admin+"/lists/guestbook/entries/"+hidden.ID+"/restore", "", auth...), http.StatusOK)
+
+				resp := do(t, "POST", admin+"/import?dry_run=true&format="+format, backups[format], auth...)
+				expectStatus(t, resp, http.StatusOK)
+				var result ImportResponse
+				decode(t, resp, &result)
+				expected := ImportResponse{DryRun: true, Lists: []ImportResult{{Key: "guestbook", Entries: 1, Held: 1}, {Key: "party", Entries: 1}}}
+				if !reflect.DeepEqual(result, expected) {
+					t.Errorf("expected %+v, got %+v", expected, result)
+				}
+				if values := listValues(t, server.URL); len(values) != 3 {
+					t.Errorf("expected a dry run to change nothing, got %q", values)
+				}
+
+				expectStatus(t, do(t, "POST", admin+"/import?format="+format, backups[format], auth...), http.StatusOK)
+				restored, _, err := s.Range(context.Background(), "guestbook", Page{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(restored, original) {
+					t.Errorf("%s: expected the entries %+v to be restored, got %+v", format, original, restored)
+				}
+				held, err := s.Held(context.Background(), "guestbook")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(held) != 1 || held[0].ID != hidden.ID || held[0].Value != hidden.Value || held[0].Status != EntryHidden {
+					t.Errorf("%s: expected the hidden entry to be restored, got %+v", format, held)
+				}
+			}
+
+			var records []AuditRecord
+			decode(t, do(t, "GET", admin+"/audit?limit=2", "", auth...), &records)
+			if len(records) != 2 || records[0].Action != ActionImport || records[0].Key != "party" || records[1].Key != "guestbook" {
+				t.Errorf("expected the imports to be audited, got %+v", records)
+			}
+		})
+	}
+}
+
+func TestImportRejectsInvalidBackups(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{AdminToken: "secret"})
+	auth := []string{"Authorization", "Bearer secret"}
+	imports := server.URL + "/api/v1/admin/import"
+	expectStatus(t, do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value": "kept"}`), http.StatusCreated)
+
+	for _, backup := range []struct{ format, body string }{
+		{FormatNDJSON, `{"key": "guestbook", "value": "valid"}` + "\n" + `{"key": "guestbook:audit", "value": "reserved"}`},
+		{FormatNDJSON, `{"key": "guestbook", "value": "valid"}` + "\nnot json"},
+		{FormatNDJSON, `{"key": "guestbook", "value": " "}`},
+		{FormatNDJSON, `{"key": "guestbook", "value": "x", "status": "deleted"}`},
+		{FormatCSV, "key,id\nguestbook,abc"},
+		{FormatCSV, "key,value,createdAt\nguestbook,hello,yesterday"},
+	} {
+		resp := do(t, "POST", imports+"?format="+backup.format, backup.body, auth...)
+		if errResp := expectError(t, resp, http.StatusBadRequest, CodeBadRequest); !strings.HasPrefix(errResp.Message, "line ") {
+			t.Errorf("expected the error to name the line, got %q", errResp.Message)
+		}
+	}
+	expectError(t, do(t, "POST", imports+"?dry_run=maybe", "", auth...), http.StatusBadRequest, CodeBadRequest)
+	if values := listValues(t, server.URL); !reflect.DeepEqual(values, []string{"kept"}) {
+		t.Errorf("expected invalid backups to change nothing, got %q", values)
+	}
+}
+
+func TestBackupBareEntries(t *testing.T) {
+	s, master := newTestSharedRedisStore(t)
+	s.maxLength = 3
+	master.RPush("guestbook", "written before records")
+	if _, err := s.Append(context.Background(), "guestbook", Entry{Value: "hello", Author: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := master.List("guestbook")
+
+	var backup bytes.Buffer
+	if err := exportLists(context.Background(), s, &backup, FormatCSV, nil); err != nil {
+		t.Fatal(err)
+	}
+	master.Del("guestbook")
+	if _, err := importLists(context.Background(), s, &backup, FormatCSV, false); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := master.List("guestbook"); !reflect.DeepEqual(after, before) {
+		t.Errorf("expected the stored values %q to be restored, got %q", before, after)
+	}
+
+	// Entries beyond the maximum length are trimmed like appended ones.
+	backup.Reset()
+	for _, value := range []string{"a", "b", "c", "d"} {
+		backup.WriteString(`{"key": "guestbook", "value": "` + value + `"}` + "\n")
+	}
+	if _, err := importLists(context.Background(), s, &backup, FormatNDJSON, false); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := s.Range(context.Background(), "guestbook", Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Entry{{Index: 0, Value: "b"}, {Index: 1, Value: "c"}, {Index: 2, Value: "d"}}; !reflect.DeepEqual(withoutMetadata(entries...), expected) {
+		t.Errorf("expected %+v, got %+v", expected, entries)
+	}
+}
+
+func TestBackupUntrackedLists(t *testing.T) {
+	s, master := newTestSharedRedisStore(t)
+	// As written before guestbooks were tracked, or by the PHP guestbook.
+	master.RPush("guestbook", "hello")
+	master.Set("messages", ",hi")
+	master.RPush("not a list name", "skipped")
+	master.Set(reservedKeyPrefix+"rate:1.2.3.4", "1")
+
+	var backup bytes.Buffer
+	if err := exportLists(context.Background(), s, &backup, FormatNDJSON, nil); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"key":"guestbook","id":"` + bareEntryID("hello") + `","value":"hello"}`; strings.TrimSpace(backup.String()) != expected {
+		t.Errorf("expected the untracked list to be exported, got %s", backup.String())
+	}
+	if names, err := (&phpStore{s}).Guestbooks(context.Background()); err != nil || !reflect.DeepEqual(names, []string{"messages"}) {
+		t.Errorf("expected the list in the PHP format to be a guestbook, got %q, %v", names, err)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+// clusterAttempts bounds how often a command is sent again after the node
+// answered with a MOVED or ASK redirect or with TRYAGAIN, as it does while
+// the cluster is resharded. clusterTryAgainDelay is the pause before a
+// command is sent again after TRYAGAIN.
+const (
+	clusterAttempts      = 3
+	clusterTryAgainDelay = 100 * time.Millisecond
+)
+
+// clusterPool is a redisPool for a Redis Cluster. Each connection is bound
+// to the node that holds its key, which keeps the MULTI transactions of the
+// store on a single node. A read only pool binds to the replicas of that
+// node instead.
+type clusterPool struct {
+	cluster  *redisc.Cluster
+	name     string
+	readOnly bool
+}
+
+// newClusterPools returns the master and slave pools of a Redis Cluster. The
+// two have separate clusters so that their commands are recorded apart. The
+// layout of the cluster is loaded right away, so that a wrong
+// -redis-cluster-nodes fails at startup rather than with the first request.
+func (c *Config) newClusterPools() (redisPool, redisPool, error) {
+	nodes := splitList(c.ClusterNodes)
+	if len(nodes) == 0 {
+		return nil, nil, errors.New("-redis-cluster-nodes is required with -redis-mode=cluster")
+	}
+	if c.RedisDB != 0 {
+		return nil, nil, errors.New("redis cluster only has database 0")
+	}
+	master := c.newClusterPool("master", nodes, false)
+	if err := master.cluster.Refresh(); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("discovering the redis cluster from %v: %w", nodes, err)
+	}
+	slave := c.newClusterPool("slave", nodes, true)
+	if err := slave.cluster.Refresh(); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, fmt.Errorf("discovering the redis cluster from %v: %w", nodes, err)
+	}
+	return master, slave, nil
+}
+
+func (c *Config) newClusterPool(name string, nodes []string, readOnly bool) clusterPool {
+	cluster := &redisc.Cluster{
+		StartupNodes: nodes,
+		DialOptions:  c.dialOptions(),
+		CreatePool: func(addr string, options ...redis.DialOption) (*redis.Pool, error) {
+			return c.newPool(name, func() (string, error) { return addr, nil }, pingOnBorrow), nil
+		},
+	}
+	return clusterPool{cluster: cluster, name: name, readOnly: readOnly}
+}
+
+// Get binds the connection right away. Should that fail, the connection is
+// returned anyway since its first command then fails with the same error.
+func (p clusterPool) Get(ctx context.Context, key string) redis.Conn {
+	conn := p.cluster.Get()
+	if p.readOnly {
+		redisc.ReadOnlyConn(conn)
+	}
+	if key != "" {
+		redisc.BindConn(conn, key)
+	}
+	return traceConn(ctx, p.name, newRedirectConn(conn))
+}
+
+// redirectConn follows the redirects of the cluster for single commands.
+// redisc cannot send the commands queued with Send again, so the Do that
+// ends a pipeline or transaction goes straight to the node; should it be
+// redirected, it fails, and redisc has refreshed the layout of the cluster
+// for the next attempt.
+type redirectConn struct {
+	redis.Conn
+	retry   redis.Conn
+	pending bool
+}
+
+func newRedirectConn(conn redis.Conn) redis.Conn {
+	retry, err := redisc.RetryConn(conn, clusterAttempts, clusterTryAgainDelay)
+	if err != nil {
+		return conn
+	}
+	return &redirectConn{Conn: conn, retry: retry}
+}
+
+func (c *redirectConn) Send(command string, args ...interface{}) error {
+	c.pending = true
+	return c.Conn.Send(command, args...)
+}
+
+func (c *redirectConn) Do(command string, args ...interface{}) (interface{}, error) {
+	if c.pending || command == "" {
+		c.pending = false
+		return c.Conn.Do(command, args...)
+	}
+	return c.retry.Do(command, args...)
+}
+
+// Dial returns a connection to any node, which is enough for a
+// subscription since PUBLISH reaches every node of the cluster.
+func (p clusterPool) Dial() (redis.Conn, error) {
+	conn, err := p.cluster.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if p.readOnly {
+		redisc.ReadOnlyConn(conn)
+	}
+	return conn, nil
+}
+
+// EachNode visits the masters even for a read only pool, since every
+// replica of a master would report its keys again.
+func (p clusterPool) EachNode(ctx context.Context, fn func(conn redis.Conn) error) error {
+	return p.cluster.EachNode(false, func(addr string, conn redis.Conn) error {
+		return fn(traceConn(ctx, p.name, conn))
+	})
+}
+
+func (p clusterPool) Ping() error {
+	return ping(p.Get(context.Background(), ""))
+}
+
+func (p clusterPool) Close() error {
+	return p.cluster.Close()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCluster(t *testing.T) {
+	// miniredis answers CLUSTER SLOTS as a cluster of one node holding
+	// every slot.
+	node := miniredis.RunT(t)
+	config := Config{RedisMode: RedisCluster, ClusterNodes: node.Addr()}
+	master, slave, err := config.NewPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slave.Close()
+	// The node has no replicas and does not know READONLY, so reads go to
+	// the master pool as well.
+	s := &redisStore{master: master, slave: master}
+	defer s.Close()
+
+	for _, value := range []string{"a", "b"} {
+		if _, err := s.Append(context.Background(), "guestbook", Entry{Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, total, err := s.Range(context.Background(), "guestbook", Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Entry{{Index: 0, Value: "a"}, {Index: 1, Value: "b"}}; total != 2 || !reflect.DeepEqual(withoutMetadata(entries...), expected) {
+		t.Errorf("expected %+v, got %+v with total %d", expected, entries, total)
+	}
+	if values, _ := node.List("guestbook"); len(values) != 2 {
+		t.Errorf("expected the entries on the node, got %q", values)
+	}
+	if names, err := s.Guestbooks(context.Background()); err != nil || !reflect.DeepEqual(names, []string{"guestbook"}) {
+		t.Errorf("expected the guestbook to be tracked, got %q, %v", names, err)
+	}
+}
+
+func TestClusterUnreachable(t *testing.T) {
+	node := miniredis.RunT(t)
+	addr := node.Addr()
+	node.Close()
+	config := Config{RedisMode: RedisCluster, ClusterNodes: addr}
+	if _, _, err := config.NewPools(); err == nil {
+		t.Error("expected an unreachable cluster to fail at startup")
+	}
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Config holds the guestbook settings. Every flag defaults to the value of an
+// environment variable so that it can also be set from a pod spec.
+type Config struct {
+	ListenAddr          string
+	LogLevel            string
+	LegacyRoutes        bool
+	Title               string
+	ThemeColor          string
+	HostInfo            string
+	ReadyTimeout        time.Duration
+	ShutdownGracePeriod time.Duration
+	Store               string
+	ListFormat          string
+	MaxValueLength      int
+	MaxListLength       int
+
+	WriteRate        float64
+	WriteBurst       int
+	RateLimitBackend string
+	TrustedProxies   string
+	ClientHashKey    string
+	Moderate         bool
+
+	Admin         bool
+	AdminToken    string
+	AdminUser     string
+	AdminPassword string
+	EnvRedact     string
+
+	MasterHost    string
+	MasterPort    int
+	SlaveHost     string
+	SlavePort     int
+	RedisPassword string
+	RedisDB       int
+	RedisTimeout  time.Duration
+
+	RedisMode      string
+	SentinelAddrs  string
+	SentinelMaster string
+	ClusterNodes   string
+
+	Consistency         string
+	ConsistencyReplicas int
+	ConsistencyTimeout  time.Duration
+
+	FailoverThreshold int
+	FailoverCooldown  time.Duration
+
+	OTLPEndpoint     string
+	TraceSampleRatio float64
+}
+
+// AddFlags registers the configuration flags on fs. It fails for an
+// environment variable that does not parse as the value of its flag, which
+// would otherwise be ignored in favour of the default.
+//
+// Like the PHP guestbook, the Redis hosts are the service DNS names unless
+// GET_HOSTS_FROM=env, in which case the REDIS_MASTER_SERVICE_HOST and
+// REDIS_SLAVE_SERVICE_HOST variables injected by Kubernetes are used.
+func (c *Config) AddFlags(fs *flag.FlagSet) error {
+	var env envParser
+	masterHost, slaveHost := "redis-master", "redis-slave"
+	masterPort, slavePort := 6379, 6379
+	sentinelAddr := "redis-sentinel:26379"
+	if os.Getenv("GET_HOSTS_FROM") == "env" {
+		masterHost = os.Getenv("REDIS_MASTER_SERVICE_HOST")
+		masterPort = env.int("REDIS_MASTER_SERVICE_PORT", masterPort)
+		slaveHost = os.Getenv("REDIS_SLAVE_SERVICE_HOST")
+		slavePort = env.int("REDIS_SLAVE_SERVICE_PORT", slavePort)
+		sentinelAddr = net.JoinHostPort(os.Getenv("REDIS_SENTINEL_SERVICE_HOST"), strconv.Itoa(env.int("REDIS_SENTINEL_SERVICE_PORT", 26379)))
+	}
+
+	fs.StringVar(&c.ListenAddr, "listen", envString("LISTEN_ADDR", ":3000"), "address to serve HTTP on ($LISTEN_ADDR)")
+	fs.StringVar(&c.LogLevel, "log-level", envString("LOG_LEVEL", "info"), "least severe level to log: \"debug\", \"info\", \"warn\" or \"error\" ($LOG_LEVEL)")
+	fs.DurationVar(&c.ReadyTimeout, "ready-timeout", env.duration("READY_TIMEOUT", time.Second), "how long /readyz waits for each Redis PING ($READY_TIMEOUT)")
+	fs.DurationVar(&c.ShutdownGracePeriod, "shutdown-grace-period", env.duration("SHUTDOWN_GRACE_PERIOD", 25*time.Second), "how long to wait for in-flight requests after SIGTERM ($SHUTDOWN_GRACE_PERIOD)")
+	fs.BoolVar(&c.LegacyRoutes, "legacy-routes", env.bool("LEGACY_ROUTES", false), "serve the deprecated GET /lrange/{key} and /rpush/{key}/{value} routes ($LEGACY_ROUTES)")
+	fs.StringVar(&c.Title, "title", envString("TITLE", "Guestbook"), "title of the UI ($TITLE)")
+	fs.StringVar(&c.ThemeColor, "theme-color", os.Getenv("THEME_COLOR"), "color of the UI as #rgb or #rrggbb, a random one for each page if empty ($THEME_COLOR)")
+	fs.StringVar(&c.HostInfo, "host-info", os.Getenv("HOST_INFO"), "text shown at the bottom of the UI to tell the replicas apart, the host name if empty ($HOST_INFO)")
+
+	fs.BoolVar(&c.Admin, "admin", env.bool("ADMIN", false), "serve the /env and /info debug routes ($ADMIN)")
+	fs.StringVar(&c.AdminToken, "admin-token", os.Getenv("ADMIN_TOKEN"), "bearer token required for the admin routes ($ADMIN_TOKEN)")
+	fs.StringVar(&c.AdminUser, "admin-user", os.Getenv("ADMIN_USER"), "basic auth user required for the admin routes ($ADMIN_USER)")
+	fs.StringVar(&c.AdminPassword, "admin-password", os.Getenv("ADMIN_PASSWORD"), "basic auth password required for the admin routes ($ADMIN_PASSWORD)")
+	fs.StringVar(&c.EnvRedact, "env-redact", envString("ENV_REDACT", "*PASSWORD*,*TOKEN*,*SECRET*,*KEY*"), "comma separated patterns of environment variable names whose values /env hides ($ENV_REDACT)")
+
+	fs.StringVar(&c.Store, "store", envString("STORE", StoreRedis), "where to keep the guestbook lists, \"redis\" or \"memory\" ($STORE)")
+	fs.StringVar(&c.ListFormat, "list-format", envString("LIST_FORMAT", ListFormatList), "how the lists are kept in Redis, \"list\" or \"php\" to share them with the PHP guestbook ($LIST_FORMAT)")
+	fs.IntVar(&c.MaxValueLength, "max-value-length", env.int("MAX_VALUE_LENGTH", 500), "longest entry in characters, 0 for no limit ($MAX_VALUE_LENGTH)")
+	fs.IntVar(&c.MaxListLength, "max-list-length", env.int("MAX_LIST_LENGTH", 0), "number of entries a list keeps before the oldest are dropped, 0 for no limit ($MAX_LIST_LENGTH)")
+	fs.Float64Var(&c.WriteRate, "write-rate", env.float("WRITE_RATE", 1), "writes per second each client may make on average, 0 for no limit ($WRITE_RATE)")
+	fs.IntVar(&c.WriteBurst, "write-burst", env.int("WRITE_BURST", 10), "writes a client may make in a burst ($WRITE_BURST)")
+	fs.StringVar(&c.RateLimitBackend, "rate-limit-backend", envString("RATE_LIMIT_BACKEND", StoreMemory), "where to keep the rate limits, \"memory\" for each replica on its own or \"redis\" to share them ($RATE_LIMIT_BACKEND)")
+	fs.StringVar(&c.TrustedProxies, "trusted-proxies", os.Getenv("TRUSTED_PROXIES"), "comma separated addresses and CIDRs of proxies whose X-Forwarded-For is believed ($TRUSTED_PROXIES)")
+	fs.StringVar(&c.ClientHashKey, "client-hash-key", os.Getenv("CLIENT_HASH_KEY"), "secret to hash the client address stored with each entry with, random if empty ($CLIENT_HASH_KEY)")
+	fs.BoolVar(&c.Moderate, "moderate", env.bool("MODERATE", false), "hold new entries back until a moderator approves them ($MODERATE)")
+	fs.StringVar(&c.MasterHost, "redis-master-host", envString("REDIS_MASTER_HOST", masterHost), "Redis master host ($REDIS_MASTER_HOST)")
+	fs.IntVar(&c.MasterPort, "redis-master-port", env.int("REDIS_MASTER_PORT_NUMBER", masterPort), "Redis master port ($REDIS_MASTER_PORT_NUMBER)")
+	fs.StringVar(&c.SlaveHost, "redis-slave-host", envString("REDIS_SLAVE_HOST", slaveHost), "Redis slave host ($REDIS_SLAVE_HOST)")
+	fs.IntVar(&c.SlavePort, "redis-slave-port", env.int("REDIS_SLAVE_PORT_NUMBER", slavePort), "Redis slave port ($REDIS_SLAVE_PORT_NUMBER)")
+	fs.StringVar(&c.RedisPassword, "redis-password", os.Getenv("REDIS_PASSWORD"), "password to AUTH with on both Redis pools ($REDIS_PASSWORD)")
+	fs.IntVar(&c.RedisDB, "redis-db", env.int("REDIS_DB", 0), "Redis database index ($REDIS_DB)")
+	fs.DurationVar(&c.RedisTimeout, "redis-timeout", env.duration("REDIS_TIMEOUT", 5*time.Second), "longest to wait for connecting to Redis and for each reply, 0 for no limit ($REDIS_TIMEOUT)")
+	fs.StringVar(&c.RedisMode, "redis-mode", envString("REDIS_MODE", RedisDirect), "how to find the Redis servers: \"direct\", \"sentinel\" or \"cluster\" ($REDIS_MODE)")
+	fs.StringVar(&c.SentinelAddrs, "redis-sentinels", envString("REDIS_SENTINELS", sentinelAddr), "comma separated host:port of the Redis Sentinels with -redis-mode=sentinel ($REDIS_SENTINELS)")
+	fs.StringVar(&c.SentinelMaster, "redis-sentinel-master", envString("REDIS_SENTINEL_MASTER", "mymaster"), "name the Sentinels monitor the master under ($REDIS_SENTINEL_MASTER)")
+	fs.StringVar(&c.ClusterNodes, "redis-cluster-nodes", os.Getenv("REDIS_CLUSTER_NODES"), "comma separated host:port of Redis Cluster nodes to discover the cluster from with -redis-mode=cluster ($REDIS_CLUSTER_NODES)")
+
+	fs.StringVar(&c.Consistency, "consistency", envString("CONSISTENCY", ConsistencyEventual), "how reads after a write see it: \"eventual\", \"master\" or \"wait\" ($CONSISTENCY)")
+	fs.IntVar(&c.ConsistencyReplicas, "consistency-replicas", env.int("CONSISTENCY_REPLICAS", 0), "number of slaves that must acknowledge a write with -consistency=wait, 0 for all slaves connected to the master ($CONSISTENCY_REPLICAS)")
+	fs.DurationVar(&c.ConsistencyTimeout, "consistency-timeout", env.duration("CONSISTENCY_TIMEOUT", 500*time.Millisecond), "longest a write waits for the slaves with -consistency=wait ($CONSISTENCY_TIMEOUT)")
+
+	fs.IntVar(&c.FailoverThreshold, "failover-threshold", env.int("FAILOVER_THRESHOLD", 3), "number of reads in a row that must fail to reach the slaves before reads go to the master, 0 to never read from the master ($FAILOVER_THRESHOLD)")
+	fs.DurationVar(&c.FailoverCooldown, "failover-cooldown", env.duration("FAILOVER_COOLDOWN", 10*time.Second), "how long reads stay on the master before the slaves are tried again ($FAILOVER_COOLDOWN)")
+
+	fs.StringVar(&c.OTLPEndpoint, "otlp-endpoint", os.Getenv("OTLP_ENDPOINT"), "URL of the OTLP/HTTP collector to send traces to, empty to disable tracing ($OTLP_ENDPOINT)")
+	fs.Float64Var(&c.TraceSampleRatio, "trace-sample-ratio", env.float("TRACE_SAMPLE_RATIO", 1), "fraction of the requests not already sampled by the caller to trace ($TRACE_SAMPLE_RATIO)")
+	return errors.Join(env.errs...)
+}
+
+// MasterAddr returns the host:port of the Redis master.
+func (c *Config) MasterAddr() string {
+	return net.JoinHostPort(c.MasterHost, strconv.Itoa(c.MasterPort))
+}
+
+// SlaveAddr returns the host:port of the Redis slaves.
+func (c *Config) SlaveAddr() string {
+	return net.JoinHostPort(c.SlaveHost, strconv.Itoa(c.SlavePort))
+}
+
+// NewPool returns a connection pool to addr that authenticates and selects
+// the configured database on every new connection. Commands are recorded in
+// the Redis metrics under the given pool name.
+func (c *Config) NewPool(name, addr string) connPool {
+	return connPool{c.newPool(name, func() (string, error) { return addr, nil }, pingOnBorrow), name}
+}
+
+// newPool is NewPool for an address that is looked up again for every new
+// connection.
+func (c *Config) newPool(name string, addr func() (string, error), testOnBorrow func(redis.Conn, time.Time) error) *redis.Pool {
+	options := c.dialOptions()
+	return &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			address, err := addr()
+			if err != nil {
+				redisErrors.WithLabelValues(name, "DIAL").Inc()
+				return nil, err
+			}
+			conn, err := redis.Dial("tcp", address, options...)
+			if err != nil {
+				redisErrors.WithLabelValues(name, "DIAL").Inc()
+				return nil, err
+			}
+			return instrumentedConn{Conn: conn, pool: name}, nil
+		},
+		TestOnBorrow: testOnBorrow,
+	}
+}
+
+// dialOptions are the options of every connection to Redis. A reply may
+// take longer than RedisTimeout by the time WAIT blocks for the slaves.
+// Subscriptions lift the read timeout, since they wait for messages for as
+// long as they last.
+func (c *Config) dialOptions() []redis.DialOption {
+	options := []redis.DialOption{redis.DialDatabase(c.RedisDB)}
+	if c.RedisPassword != "" {
+		options = append(options, redis.DialPassword(c.RedisPassword))
+	}
+	if c.RedisTimeout > 0 {
+		readTimeout := c.RedisTimeout
+		if c.Consistency == ConsistencyWait {
+			readTimeout += c.ConsistencyTimeout
+		}
+		options = append(options,
+			redis.DialConnectTimeout(c.RedisTimeout),
+			redis.DialReadTimeout(readTimeout),
+			redis.DialWriteTimeout(c.RedisTimeout))
+	}
+	return options
+}
+
+// pingIdleAfter is how long a pooled connection may sit idle before it is
+// checked with a PING when it is borrowed again. Recently used connections
+// are handed out as they are, which spares every command a round trip.
+const pingIdleAfter = time.Minute
+
+// pingOnBorrow checks connections that have been idle for pingIdleAfter, as
+// the server or a load balancer in between may have dropped them meanwhile.
+func pingOnBorrow(c redis.Conn, t time.Time) error {
+	if time.Since(t) < pingIdleAfter {
+		return nil
+	}
+	_, err := c.Do("PING")
+	return err
+}
+
+// splitList splits a comma separated flag value, ignoring empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+func envString(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// envParser reads the defaults of flags from environment variables, and
+// keeps the errors of those that are set but do not parse.
+type envParser struct {
+	errs []error
+}
+
+// lookup returns the variable, or false if it is unset or empty.
+func (e *envParser) lookup(name string) (string, bool) {
+	v := os.Getenv(name)
+	return v, v != ""
+}
+
+func (e *envParser) fail(name, value string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("invalid $%s %q: %w", name, value, err))
+}
+
+func (e *envParser) int(name string, def int) int {
+	s, ok := e.lookup(name)
+	if !ok {
+		return def
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		e.fail(name, s, err)
+		return def
+	}
+	return v
+}
+
+func (e *envParser) float(name string, def float64) float64 {
+	s, ok := e.lookup(name)
+	if !ok {
+		return def
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		e.fail(name, s, err)
+		return def
+	}
+	return v
+}
+
+func (e *envParser) duration(name string, def time.Duration) time.Duration {
+	s, ok := e.lookup(name)
+	if !ok {
+		return def
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		e.fail(name, s, err)
+		return def
+	}
+	return v
+}
+
+func (e *envParser) bool(name string, def bool) bool {
+	s, ok := e.lookup(name)
+	if !ok {
+		return def
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		e.fail(name, s, err)
+		return def
+	}
+	return v
+}
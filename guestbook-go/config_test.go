@@ -0,0 +1,79 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	t.Setenv("MAX_LIST_LENGTH", "100")
+	t.Setenv("READY_TIMEOUT", "3s")
+	t.Setenv("MODERATE", "true")
+	// Set by Kubernetes for a service named redis-master, in the form of a
+	// Docker link.
+	t.Setenv("REDIS_MASTER_PORT", "tcp://10.0.0.1:6379")
+	var config Config
+	if err := config.AddFlags(flag.NewFlagSet("guestbook", flag.ContinueOnError)); err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxListLength != 100 || config.ReadyTimeout != 3*time.Second || !config.Moderate || config.MasterPort != 6379 {
+		t.Errorf("expected the defaults from the environment, got %+v", config)
+	}
+	if config.LegacyRoutes {
+		t.Error("expected the legacy routes to be off by default")
+	}
+}
+
+func TestInvalidEnv(t *testing.T) {
+	t.Setenv("MAX_LIST_LENGTH", "lots")
+	t.Setenv("READY_TIMEOUT", "3")
+	t.Setenv("MODERATE", "maybe")
+	t.Setenv("WRITE_RATE", "fast")
+	var config Config
+	err := config.AddFlags(flag.NewFlagSet("guestbook", flag.ContinueOnError))
+	if err == nil {
+		t.Fatal("expected invalid environment variables to be rejected")
+	}
+	for _, name := range []string{"$MAX_LIST_LENGTH", "$READY_TIMEOUT", "$MODERATE", "$WRITE_RATE"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to name %s, got %v", name, err)
+		}
+	}
+}
+
+func TestPingOnBorrow(t *testing.T) {
+	server := miniredis.RunT(t)
+	conn, err := redis.Dial("tcp", server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server.Close()
+	if err := pingOnBorrow(conn, time.Now()); err != nil {
+		t.Errorf("expected a recently used connection not to be checked, got %v", err)
+	}
+	if err := pingOnBorrow(conn, time.Now().Add(-pingIdleAfter)); err == nil {
+		t.Error("expected an idle connection to the stopped server to fail the check")
+	}
+}
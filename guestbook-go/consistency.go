@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Consistency modes accepted by the -consistency flag. They decide whether
+// a client sees its own write when it reads the list right after it.
+const (
+	// ConsistencyEventual reads from the slaves without waiting, so a
+	// response may miss an entry that has not been replicated yet.
+	ConsistencyEventual = "eventual"
+	// ConsistencyMaster answers the reads that follow a write, such as the
+	// list returned by /rpush, from the master.
+	ConsistencyMaster = "master"
+	// ConsistencyWait holds every write until enough slaves have
+	// acknowledged it, or the timeout passes.
+	ConsistencyWait = "wait"
+)
+
+func validateConsistency(mode string) error {
+	switch mode {
+	case ConsistencyEventual, ConsistencyMaster, ConsistencyWait:
+		return nil
+	}
+	return fmt.Errorf("unknown consistency %q, must be %q, %q or %q", mode, ConsistencyEventual, ConsistencyMaster, ConsistencyWait)
+}
+
+// afterWriteRanger is implemented by stores whose reads may not yet see
+// their own writes.
+type afterWriteRanger interface {
+	// RangeAfterWrite is Range for a response to a write, which must
+	// include that write.
+	RangeAfterWrite(ctx context.Context, key string, page Page) ([]Entry, int, error)
+}
+
+// rangeAfterWrite reads the page of a list that has just been written to.
+func rangeAfterWrite(ctx context.Context, key string, page Page) ([]Entry, int, error) {
+	if r, ok := store.(afterWriteRanger); ok {
+		return r.RangeAfterWrite(ctx, key, page)
+	}
+	return store.Range(ctx, key, page)
+}
+
+// waitForReplicas blocks until replicas slaves, or every slave connected to
+// the master if replicas is 0, have acknowledged the writes made so far on
+// conn, or until timeout. Running out of time is not an error: the write has
+// succeeded on the master and the slaves will catch up, it is only logged and
+// counted.
+func waitForReplicas(conn redis.Conn, replicas int, timeout time.Duration) {
+	if replicas == 0 {
+		info, err := redis.String(conn.Do("INFO", "replication"))
+		if err == nil {
+			replicas, err = connectedSlaves(info)
+		}
+		if err != nil {
+			slog.Warn("finding the connected slaves failed", "error", err)
+			return
+		}
+		if replicas == 0 {
+			return
+		}
+	}
+	// WAIT treats a timeout of 0 as forever.
+	ms := timeout.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	acked, err := redis.Int(conn.Do("WAIT", replicas, ms))
+	if err != nil {
+		slog.Warn("waiting for replication failed", "error", err)
+		return
+	}
+	if acked < replicas {
+		replicationWaitTimeouts.Inc()
+		slog.Warn("write not acknowledged by enough slaves", "acknowledged", acked, "required", replicas, "timeout", timeout.String())
+	}
+}
+
+// connectedSlaves returns the number of slaves connected to a master from its
+// INFO replication output.
+func connectedSlaves(info string) (int, error) {
+	slaves, ok := parseInfo(info)["replication"]["connected_slaves"]
+	if !ok {
+		return 0, errors.New("INFO does not report connected_slaves")
+	}
+	return strconv.Atoi(slaves)
+}
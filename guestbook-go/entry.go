@@ -0,0 +1,160 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAuthorLength is the longest author name in characters.
+const maxAuthorLength = 50
+
+// entryRecord is how an entry is stored, in a list or held back from it.
+// Lists written before entries had metadata hold the bare values instead.
+type entryRecord struct {
+	ID         string    `json:"id"`
+	Text       string    `json:"text"`
+	Author     string    `json:"author,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ClientHash string    `json:"clientHash,omitempty"`
+	Status     string    `json:"status,omitempty"`
+}
+
+// encodeEntry returns the stored form of entry, which is given an ID and
+// creation time unless it has them already.
+func encodeEntry(entry Entry) (Entry, string, error) {
+	if entry.ID == "" {
+		entry.ID = newEntryID()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
+	record, err := json.Marshal(entryRecord{
+		ID:         entry.ID,
+		Text:       entry.Value,
+		Author:     entry.Author,
+		CreatedAt:  entry.CreatedAt,
+		ClientHash: entry.ClientHash,
+		Status:     entry.Status,
+	})
+	return entry, string(record), err
+}
+
+// decodeEntry returns the entry at index stored as value. Anything that is
+// not a record with an ID is a bare value from before records were stored,
+// whose ID is derived from the value so that it can still be moderated.
+func decodeEntry(index int, value string) Entry {
+	if strings.HasPrefix(value, "{") {
+		var record entryRecord
+		if err := json.Unmarshal([]byte(value), &record); err == nil && record.ID != "" {
+			return Entry{
+				Index:      index,
+				ID:         record.ID,
+				Value:      record.Text,
+				Author:     record.Author,
+				CreatedAt:  record.CreatedAt,
+				ClientHash: record.ClientHash,
+				Status:     record.Status,
+			}
+		}
+	}
+	return Entry{Index: index, ID: bareEntryID(value), Value: value}
+}
+
+// bareEntryID returns the ID of an entry stored as the bare value. Entries
+// with the same value share it, which is harmless since they are alike.
+func bareEntryID(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "v" + hex.EncodeToString(sum[:8])
+}
+
+// lastIndexOf returns the index of the last entry in list with the given ID,
+// or -1 if there is none. The last is the newest of the entries stored as
+// bare values that share the ID of equal values.
+func lastIndexOf(list []Entry, id string) int {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func newEntryID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// entryBuilder validates new entries and records who wrote them.
+type entryBuilder struct {
+	maxValueLength int
+	// moderate holds new entries back until a moderator approves them.
+	moderate bool
+	trusted  trustedProxies
+	// hashKey keys the hash of the client address, so that the address
+	// cannot be recovered by hashing every possible one.
+	hashKey []byte
+}
+
+// newEntryBuilder returns the entryBuilder for the configuration. Without
+// a -client-hash-key the key is random, so the hashes differ between
+// replicas and restarts.
+func (c *Config) newEntryBuilder() (*entryBuilder, error) {
+	trusted, err := parseNets(c.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(c.ClientHashKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &entryBuilder{maxValueLength: c.MaxValueLength, moderate: c.Moderate, trusted: trusted, hashKey: key}, nil
+}
+
+// build returns the entry for value written by author, who may be empty,
+// from the client of req.
+func (b *entryBuilder) build(req *http.Request, value, author string) (Entry, error) {
+	value, err := validateValue(value, b.maxValueLength)
+	if err != nil {
+		return Entry{}, err
+	}
+	if strings.TrimSpace(author) != "" {
+		if author, err = validateText("author", author, maxAuthorLength); err != nil {
+			return Entry{}, err
+		}
+	}
+	entry := Entry{Value: value, Author: strings.TrimSpace(author), ClientHash: b.clientHash(req)}
+	if b.moderate {
+		entry.Status = EntryPending
+	}
+	return entry, nil
+}
+
+func (b *entryBuilder) clientHash(req *http.Request) string {
+	mac := hmac.New(sha256.New, b.hashKey)
+	mac.Write([]byte(b.trusted.clientIP(req)))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryRecords(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := Entry{ID: "abc", Value: "hello", Author: "Ann", CreatedAt: created, ClientHash: "0123"}
+	stored, record, err := encodeEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != entry {
+		t.Errorf("expected the ID and creation time to be kept, got %+v", stored)
+	}
+	if expected := `{"id":"abc","text":"hello","author":"Ann","createdAt":"2026-01-02T03:04:05Z","clientHash":"0123"}`; record != expected {
+		t.Errorf("expected the record %s, got %s", expected, record)
+	}
+	entry.Index = 3
+	if decoded := decodeEntry(3, record); decoded != entry {
+		t.Errorf("expected %+v, got %+v", entry, decoded)
+	}
+
+	stored, _, _ = encodeEntry(Entry{Value: "hello"})
+	if stored.ID == "" || stored.CreatedAt.IsZero() {
+		t.Errorf("expected a new ID and creation time, got %+v", stored)
+	}
+
+	// Bare values from before records, even ones that look like JSON.
+	for _, value := range []string{"hello", "{", `{"text": "no id"}`} {
+		if decoded := decodeEntry(1, value); decoded != (Entry{Index: 1, ID: bareEntryID(value), Value: value}) {
+			t.Errorf("expected %q to be read as a bare value, got %+v", value, decoded)
+		}
+	}
+}
+
+func TestEntryMetadata(t *testing.T) {
+	s, master := newTestSharedRedisStore(t)
+	master.RPush("guestbook", "written before records")
+	server := newTestServerWithConfig(t, s, &Config{ClientHashKey: "secret"})
+	entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+	resp := do(t, "POST", entries, `{"value": "hello", "author": " Ann "}`)
+	expectStatus(t, resp, http.StatusCreated)
+	var body map[string]interface{}
+	decode(t, resp, &body)
+	if body["author"] != "Ann" || body["id"] == nil || body["createdAt"] == nil {
+		t.Errorf("expected the author, ID and creation time, got %v", body)
+	}
+	if _, ok := body["clientHash"]; ok {
+		t.Errorf("expected the client hash not to be served, got %v", body)
+	}
+	expectError(t, do(t, "POST", entries, `{"value": "hello", "author": "`+strings.Repeat("a", maxAuthorLength+1)+`"}`), http.StatusBadRequest, CodeBadRequest)
+
+	values, _ := master.List("guestbook")
+	var record entryRecord
+	if err := json.Unmarshal([]byte(values[1]), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", values[1], err)
+	}
+	if record.Text != "hello" || record.ClientHash == "" || strings.Contains(values[1], "127.0.0.1") {
+		t.Errorf("expected a record with a hashed client address, got %s", values[1])
+	}
+
+	// The bare value is still served, without metadata.
+	list, _, err := s.Range(context.Background(), "guestbook", Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Entry{{Index: 0, Value: "written before records"}, {Index: 1, Value: "hello"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+		t.Errorf("expected %+v, got %+v", expected, list)
+	}
+	if list[0].ID != bareEntryID("written before records") || !list[0].CreatedAt.IsZero() || list[1].Author != "Ann" {
+		t.Errorf("expected metadata only on the record, got %+v", list)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Error codes reported in the "code" field of an error response.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeBadKey           = "bad_key"
+	CodeNotFound         = "not_found"
+	CodeRedisUnavailable = "redis_unavailable"
+	CodeRedisError       = "redis_error"
+	CodeTooLarge         = "too_large"
+	CodeEncodeFailed     = "encode_failed"
+	CodeInternal         = "internal"
+)
+
+// maxKeyLength bounds the length of a list name taken from the URL.
+const maxKeyLength = 256
+
+// maxBodyBytes bounds the size of a JSON request body.
+const maxBodyBytes = 64 << 10
+
+// Error is an error that knows the HTTP status and code it is reported with.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorResponse is the JSON body written for a failed request.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId"`
+}
+
+func badRequest(code, format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func encodeFailed(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeEncodeFailed, Message: "failed to encode response", Err: err}
+}
+
+// redisError classifies an error returned by a Redis pool. Failures to reach
+// the server become 503s so that callers can tell an outage from a bug.
+func redisError(err error) *Error {
+	var redisErr redis.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "WRONGTYPE"):
+		return &Error{Status: http.StatusBadRequest, Code: CodeBadKey, Message: "key does not hold a list", Err: err}
+	case errors.As(err, &redisErr):
+		return &Error{Status: http.StatusInternalServerError, Code: CodeRedisError, Message: "redis command failed", Err: err}
+	case errors.As(err, &netErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, redis.ErrPoolExhausted), errors.Is(err, net.ErrClosed), errors.Is(err, errNoServer):
+		return &Error{Status: http.StatusServiceUnavailable, Code: CodeRedisUnavailable, Message: "redis is unavailable", Err: err}
+	}
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+// validateKey rejects list names that are empty, overly long, reserved or
+// contain anything but ASCII letters, digits and the punctuation in
+// keyPunctuation.
+func validateKey(key string) error {
+	if key == "" {
+		return badRequest(CodeBadKey, "key must not be empty")
+	}
+	if len(key) > maxKeyLength {
+		return badRequest(CodeBadKey, "key must be at most %d bytes", maxKeyLength)
+	}
+	if strings.IndexFunc(key, invalidKeyRune) >= 0 {
+		return badRequest(CodeBadKey, "key may only contain ASCII letters, digits and %q", keyPunctuation)
+	}
+	if strings.HasPrefix(key, reservedKeyPrefix) {
+		return badRequest(CodeBadKey, "key must not start with %q", reservedKeyPrefix)
+	}
+	return nil
+}
+
+// reservedKeyPrefix starts the Redis keys the guestbook keeps for itself,
+// such as the rate limits, so that no list can be stored over them.
+const reservedKeyPrefix = "guestbook:"
+
+// keyPunctuation are the characters besides letters and digits allowed in
+// a list name.
+const keyPunctuation = "-_.:"
+
+func invalidKeyRune(r rune) bool {
+	return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune(keyPunctuation, r))
+}
+
+// validateValue returns value without surrounding white space, and rejects
+// it if that leaves it empty, longer than maxLength characters or with
+// control characters or invalid UTF-8. A maxLength of 0 means no limit.
+// Values are stored as plain text, so clients must escape them for HTML.
+func validateValue(value string, maxLength int) (string, error) {
+	return validateText("value", value, maxLength)
+}
+
+// validateText is validateValue for the text of the named field.
+func validateText(field, text string, maxLength int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", badRequest(CodeBadRequest, "%s must not be empty", field)
+	}
+	if !utf8.ValidString(text) {
+		return "", badRequest(CodeBadRequest, "%s must be valid UTF-8", field)
+	}
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		return "", badRequest(CodeBadRequest, "%s must be at most %d characters", field, maxLength)
+	}
+	if strings.IndexFunc(text, unicode.IsControl) >= 0 {
+		return "", badRequest(CodeBadRequest, "%s must not contain control characters", field)
+	}
+	return text, nil
+}
+
+// appHandler is an http.Handler that reports a returned error as a JSON
+// error response instead of panicking.
+type appHandler func(http.ResponseWriter, *http.Request) error
+
+func (fn appHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if err := fn(rw, req); err != nil {
+		writeError(rw, req, err)
+	}
+}
+
+func writeError(rw http.ResponseWriter, req *http.Request, err error) {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		appErr = &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error", Err: err}
+	}
+	id := requestID(req)
+	level := slog.LevelWarn
+	if appErr.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(req.Context(), level, "request failed",
+		"request_id", id,
+		"method", req.Method,
+		"path", req.URL.Path,
+		"status", appErr.Status,
+		"code", appErr.Code,
+		"error", err.Error())
+
+	body, _ := json.Marshal(ErrorResponse{Code: appErr.Code, Message: appErr.Message, RequestID: id})
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("X-Request-ID", id)
+	rw.WriteHeader(appErr.Status)
+	rw.Write(body)
+}
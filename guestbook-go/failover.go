@@ -0,0 +1,98 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// slaveBreaker is a circuit breaker in front of the slave pool. After
+// threshold reads in a row have failed to reach a slave, reads go to the
+// master instead. Once cooldown has passed a read is let through to the
+// slaves again, and reads switch back as soon as one succeeds.
+type slaveBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	// openedAt is when reads last went to the master, zero while they go
+	// to the slaves.
+	openedAt time.Time
+}
+
+func newSlaveBreaker(threshold int, cooldown time.Duration) *slaveBreaker {
+	b := &slaveBreaker{threshold: threshold, cooldown: cooldown}
+	setReadPool("slave")
+	return b
+}
+
+// allow reports whether the next read should try the slaves.
+func (b *slaveBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openedAt.IsZero() || time.Since(b.openedAt) >= b.cooldown
+}
+
+func (b *slaveBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	if !b.openedAt.IsZero() {
+		b.openedAt = time.Time{}
+		setReadPool("slave")
+		slog.Info("slaves have recovered, reading from the slaves again")
+	}
+}
+
+func (b *slaveBreaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if !b.openedAt.IsZero() {
+		// A trial read after the cooldown failed, wait for another one.
+		b.openedAt = time.Now()
+		return
+	}
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		slaveFailovers.Inc()
+		setReadPool("master")
+		slog.Warn("slaves are unavailable, reading from the master", "failures", b.failures, "cooldown", b.cooldown.String(), "error", err.Error())
+	}
+}
+
+// setReadPool records which pool reads are currently sent to.
+func setReadPool(pool string) {
+	for _, p := range []string{"master", "slave"} {
+		value := 0.0
+		if p == pool {
+			value = 1
+		}
+		readPool.WithLabelValues(p).Set(value)
+	}
+}
+
+// isUnavailable reports whether err means that Redis could not be reached,
+// as opposed to a command that Redis rejected.
+func isUnavailable(err error) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Code == CodeRedisUnavailable
+}
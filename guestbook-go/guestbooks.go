@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CodeConflict is the error code for creating a guestbook that exists.
+const CodeConflict = "conflict"
+
+// Guestbook is the JSON representation of a guestbook.
+type Guestbook struct {
+	Name string `json:"name"`
+	// URL is where the guestbook is shown in the UI.
+	URL string `json:"url"`
+}
+
+func newGuestbook(name string) Guestbook {
+	return Guestbook{Name: name, URL: "/g/" + name}
+}
+
+func GuestbookListHandler(rw http.ResponseWriter, req *http.Request) error {
+	names, err := store.Guestbooks(req.Context())
+	if err != nil {
+		return err
+	}
+	guestbooks := make([]Guestbook, len(names))
+	for i, name := range names {
+		guestbooks[i] = newGuestbook(name)
+	}
+	return writeJSON(rw, http.StatusOK, guestbooks)
+}
+
+func GuestbookCreateHandler(rw http.ResponseWriter, req *http.Request) error {
+	var guestbook Guestbook
+	if err := decodeBody(rw, req, &guestbook); err != nil {
+		return err
+	}
+	if err := validateKey(guestbook.Name); err != nil {
+		return err
+	}
+
+	created, err := store.CreateGuestbook(req.Context(), guestbook.Name)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return &Error{Status: http.StatusConflict, Code: CodeConflict, Message: fmt.Sprintf("guestbook %q already exists", guestbook.Name)}
+	}
+	rw.Header().Set("Location", "/api/v1/lists/"+guestbook.Name+"/entries")
+	return writeJSON(rw, http.StatusCreated, newGuestbook(guestbook.Name))
+}
+
+func GuestbookDeleteHandler(rw http.ResponseWriter, req *http.Request) error {
+	name := mux.Vars(req)["name"]
+	if err := validateKey(name); err != nil {
+		return err
+	}
+	if err := store.DeleteGuestbook(req.Context(), name); err == ErrNotFound {
+		return notFound("guestbook %q not found", name)
+	} else if err != nil {
+		return err
+	}
+	rw.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DependencyStatus is the result of checking a single dependency.
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Readiness is the JSON body returned by /readyz.
+type Readiness struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// HealthzHandler reports that the process is up. It deliberately does not
+// look at Redis so that a Redis outage does not get every pod restarted.
+func HealthzHandler(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write([]byte("ok\n"))
+}
+
+// HealthCheck tests a single dependency. The store keeps working without an
+// optional dependency, so its failure is reported without failing readiness.
+type HealthCheck struct {
+	Check    func() error
+	Optional bool
+}
+
+// HealthChecker is implemented by stores that depend on other services.
+// Each check is named after the dependency it tests.
+type HealthChecker interface {
+	HealthChecks() map[string]HealthCheck
+}
+
+// newReadyzHandler returns a handler that runs the health checks of the
+// store, waiting at most timeout for each, and fails with 503 unless all of
+// the required ones pass. A failed optional check only marks the status as
+// degraded. For the Redis store that means the master answers a PING, and
+// so do the slaves unless reads fall back to the master.
+func newReadyzHandler(store Store, timeout time.Duration) appHandler {
+	return func(rw http.ResponseWriter, req *http.Request) error {
+		var checks map[string]HealthCheck
+		if checker, ok := store.(HealthChecker); ok {
+			checks = checker.HealthChecks()
+		}
+		readiness := Readiness{Status: "ok", Dependencies: make(map[string]DependencyStatus, len(checks))}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check HealthCheck) {
+				defer wg.Done()
+				status := runCheck(req.Context(), check.Check, timeout)
+				mu.Lock()
+				defer mu.Unlock()
+				readiness.Dependencies[name] = status
+				if status.Error == "" {
+					return
+				}
+				if !check.Optional {
+					readiness.Status = "unavailable"
+				} else if readiness.Status == "ok" {
+					readiness.Status = "degraded"
+				}
+			}(name, check)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if readiness.Status == "unavailable" {
+			status = http.StatusServiceUnavailable
+		}
+		return writeJSON(rw, status, readiness)
+	}
+}
+
+// runCheck runs check and gives up after timeout. Dialing a Redis pool has
+// no deadline of its own, so the check runs in its own goroutine.
+func runCheck(ctx context.Context, check func() error, timeout time.Duration) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := DependencyStatus{Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		status.Status = "unavailable"
+		status.Error = err.Error()
+	}
+	return status
+}
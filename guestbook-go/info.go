@@ -0,0 +1,125 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ServerInfo is the INFO output of one of the servers a store uses. Err is
+// set instead of Text for a server that could not be asked.
+type ServerInfo struct {
+	Role string
+	Text string
+	Err  error
+}
+
+// InfoSection holds the fields of one section of INFO output.
+type InfoSection map[string]string
+
+// NodeInfo is the parsed INFO output of one server, keyed by the lower case
+// section name, or the error that prevented getting it.
+type NodeInfo struct {
+	Sections map[string]InfoSection `json:"sections,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// InfoResponse is the JSON body returned by /info.
+type InfoResponse struct {
+	Servers map[string]NodeInfo `json:"servers"`
+	// ReplicationLagBytes is how far the replication offset of the slave
+	// trails the master's, when both are known.
+	ReplicationLagBytes *int64 `json:"replicationLagBytes,omitempty"`
+}
+
+// parseInfo splits INFO output into its "# Section" headed sections of
+// "field:value" lines.
+func parseInfo(text string) map[string]InfoSection {
+	sections := make(map[string]InfoSection)
+	section := InfoSection{}
+	sections[""] = section
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			section = InfoSection{}
+			sections[strings.ToLower(strings.TrimSpace(line[1:]))] = section
+		default:
+			if field, value, ok := strings.Cut(line, ":"); ok {
+				section[field] = value
+			}
+		}
+	}
+	if len(sections[""]) == 0 {
+		delete(sections, "")
+	}
+	return sections
+}
+
+// replicationLag compares the master_repl_offset of the master with the
+// slave_repl_offset of the slave.
+func replicationLag(servers map[string]NodeInfo) *int64 {
+	master, err := strconv.ParseInt(servers["master"].Sections["replication"]["master_repl_offset"], 10, 64)
+	if err != nil {
+		return nil
+	}
+	slave, err := strconv.ParseInt(servers["slave"].Sections["replication"]["slave_repl_offset"], 10, 64)
+	if err != nil {
+		return nil
+	}
+	lag := master - slave
+	return &lag
+}
+
+// InfoHandler reports the INFO output of the servers behind the store as
+// JSON, or as the original text with format=text.
+func InfoHandler(rw http.ResponseWriter, req *http.Request) error {
+	infos, err := store.Info(req.Context())
+	if err != nil {
+		return err
+	}
+
+	if req.URL.Query().Get("format") == "text" {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, info := range infos {
+			fmt.Fprintf(rw, "# guestbook:%s\r\n", info.Role)
+			if info.Err != nil {
+				fmt.Fprintf(rw, "error:%v\r\n\r\n", info.Err)
+				continue
+			}
+			fmt.Fprintf(rw, "%s\r\n\r\n", strings.TrimRight(info.Text, "\r\n"))
+		}
+		return nil
+	}
+
+	resp := InfoResponse{Servers: make(map[string]NodeInfo, len(infos))}
+	for _, info := range infos {
+		if info.Err != nil {
+			resp.Servers[info.Role] = NodeInfo{Error: info.Err.Error()}
+			continue
+		}
+		resp.Servers[info.Role] = NodeInfo{Sections: parseInfo(info.Text)}
+	}
+	resp.ReplicationLagBytes = replicationLag(resp.Servers)
+	return writeJSON(rw, http.StatusOK, resp)
+}
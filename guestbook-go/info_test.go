@@ -0,0 +1,67 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const masterInfo = "# Server\r\nredis_version:3.2.12\r\nuptime_in_seconds:42\r\n\r\n" +
+	"# Replication\r\nrole:master\r\nconnected_slaves:1\r\n" +
+	"slave0:ip=10.0.0.2,port=6379,state=online,offset=1180,lag=0\r\nmaster_repl_offset:1234\r\n"
+
+const slaveInfo = "# Replication\r\nrole:slave\r\nmaster_link_status:up\r\nslave_repl_offset:1180\r\n"
+
+func TestParseInfo(t *testing.T) {
+	expected := map[string]InfoSection{
+		"server": {"redis_version": "3.2.12", "uptime_in_seconds": "42"},
+		"replication": {
+			"role":               "master",
+			"connected_slaves":   "1",
+			"slave0":             "ip=10.0.0.2,port=6379,state=online,offset=1180,lag=0",
+			"master_repl_offset": "1234",
+		},
+	}
+	if sections := parseInfo(masterInfo); !reflect.DeepEqual(sections, expected) {
+		t.Errorf("expected %v, got %v", expected, sections)
+	}
+}
+
+func TestConnectedSlaves(t *testing.T) {
+	if n, err := connectedSlaves(masterInfo); err != nil || n != 1 {
+		t.Errorf("expected 1 connected slave, got %d, %v", n, err)
+	}
+	if _, err := connectedSlaves(slaveInfo); err == nil {
+		t.Error("expected an error without connected_slaves")
+	}
+}
+
+func TestReplicationLag(t *testing.T) {
+	servers := map[string]NodeInfo{
+		"master": {Sections: parseInfo(masterInfo)},
+		"slave":  {Sections: parseInfo(slaveInfo)},
+	}
+	if lag := replicationLag(servers); lag == nil || *lag != 54 {
+		t.Errorf("expected a lag of 54, got %v", lag)
+	}
+
+	servers["slave"] = NodeInfo{Error: "connection refused"}
+	if lag := replicationLag(servers); lag != nil {
+		t.Errorf("expected no lag without slave info, got %d", *lag)
+	}
+}
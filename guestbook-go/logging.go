@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/codegangsta/negroni"
+)
+
+// maxRequestIDLength bounds the length of a caller supplied request ID.
+const maxRequestIDLength = 128
+
+// requestInfo is what the middlewares learn about a request, shared through
+// its context so that the access log can report it.
+type requestInfo struct {
+	id    string
+	route string
+}
+
+type requestInfoKey struct{}
+
+// newLogger returns a logger that writes JSON lines for level and above.
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// logRequests gives every request an ID, which is the caller supplied
+// X-Request-ID if there is a usable one, returns it in the X-Request-ID
+// response header and writes an access log line once the request has been
+// served.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		info := &requestInfo{id: req.Header.Get("X-Request-ID"), route: "unknown"}
+		if !validRequestID(info.id) {
+			info.id = newRequestID()
+		}
+		req = req.WithContext(context.WithValue(req.Context(), requestInfoKey{}, info))
+		rw.Header().Set("X-Request-ID", info.id)
+
+		start := time.Now()
+		nrw := negroni.NewResponseWriter(rw)
+		next.ServeHTTP(nrw, req)
+
+		level := slog.LevelInfo
+		if nrw.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(req.Context(), level, "request",
+			slog.String("request_id", info.id),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("route", info.route),
+			slog.Int("status", nrw.Status()),
+			slog.Int("bytes", nrw.Size()),
+			slog.Float64("latency_seconds", time.Since(start).Seconds()),
+			slog.String("remote_addr", req.RemoteAddr),
+		)
+	})
+}
+
+// recoverPanics turns a panic in next into a logged 500 response instead of
+// a dropped connection.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			requestLogger(req).Error("panic serving request", "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+			writeError(rw, req, &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error", Err: fmt.Errorf("panic: %v", v)})
+		}()
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// setRoute records the route template that matched the request for the
+// access log.
+func setRoute(req *http.Request, route string) {
+	if info, ok := req.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.route = route
+	}
+}
+
+// requestID returns the ID logRequests gave the request. Requests that did
+// not pass through it get the caller supplied X-Request-ID, or a random one.
+func requestID(req *http.Request) string {
+	if info, ok := req.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	if id := req.Header.Get("X-Request-ID"); validRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// requestLogger returns the default logger with the ID of req attached.
+func requestLogger(req *http.Request) *slog.Logger {
+	return slog.With("request_id", requestID(req))
+}
+
+// validRequestID accepts caller supplied IDs that are safe to log and echo.
+func validRequestID(id string) bool {
+	return id != "" && len(id) <= maxRequestIDLength && strings.IndexFunc(id, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsPrint(r)
+	}) < 0
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
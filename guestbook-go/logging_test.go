@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer that the server's goroutines can log to.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the logged JSON lines whose msg is msg.
+func (b *syncBuffer) lines(t *testing.T, msg string) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		if line["msg"] == msg {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// captureLogs sends the default logger to the returned buffer for the rest
+// of the test.
+func captureLogs(t *testing.T) *syncBuffer {
+	logs := &syncBuffer{}
+	previous := slog.Default()
+	slog.SetDefault(newLogger(logs, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return logs
+}
+
+func TestRequestLogging(t *testing.T) {
+	logs := captureLogs(t)
+	store = newMemoryStore()
+	entries, err := (&Config{}).newEntryBuilder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui, err := (&Config{}).newUI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{AdminToken: "secret"}
+	server := httptest.NewServer(logRequests(recoverPanics(newRouter(config, entries, ui, nil, make(chan struct{})))))
+	defer server.Close()
+
+	resp := do(t, "DELETE", server.URL+"/api/v1/admin/lists/guestbook/entries/missing", "", "X-Request-ID", "abc123", "Authorization", "Bearer secret")
+	errResp := expectError(t, resp, http.StatusNotFound, CodeNotFound)
+	if errResp.RequestID != "abc123" || resp.Header.Get("X-Request-ID") != "abc123" {
+		t.Errorf("expected the request ID to be echoed, got %q and header %q", errResp.RequestID, resp.Header.Get("X-Request-ID"))
+	}
+
+	// An unusable ID is replaced.
+	resp = do(t, "GET", server.URL+"/api/v1/lists/guestbook/entries", "", "X-Request-ID", strings.Repeat("x", maxRequestIDLength+1))
+	expectStatus(t, resp, http.StatusOK)
+	generated := resp.Header.Get("X-Request-ID")
+	if generated == "" || len(generated) > maxRequestIDLength {
+		t.Errorf("expected a generated request ID, got %q", generated)
+	}
+
+	failures := logs.lines(t, "request failed")
+	if len(failures) != 1 || failures[0]["request_id"] != "abc123" || failures[0]["level"] != "WARN" || failures[0]["code"] != CodeNotFound {
+		t.Errorf("expected the failure logged with the request ID, got %v", failures)
+	}
+	requests := logs.lines(t, "request")
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 access log lines, got %v", requests)
+	}
+	first := requests[0]
+	for field, expected := range map[string]interface{}{
+		"level":      "INFO",
+		"request_id": "abc123",
+		"method":     "DELETE",
+		"route":      "/api/v1/admin/lists/{key}/entries/{id}",
+		"status":     float64(http.StatusNotFound),
+	} {
+		if first[field] != expected {
+			t.Errorf("access log %s: expected %v, got %v", field, expected, first[field])
+		}
+	}
+	for _, field := range []string{"latency_seconds", "remote_addr", "path"} {
+		if _, ok := first[field]; !ok {
+			t.Errorf("access log is missing %s: %v", field, first)
+		}
+	}
+	if requests[1]["request_id"] != generated {
+		t.Errorf("expected the generated request ID %q in the access log, got %v", generated, requests[1]["request_id"])
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	logs := captureLogs(t)
+	server := httptest.NewServer(logRequests(recoverPanics(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))))
+	defer server.Close()
+
+	expectError(t, do(t, "GET", server.URL, "", "X-Request-ID", "abc123"), http.StatusInternalServerError, CodeInternal)
+	if panics := logs.lines(t, "panic serving request"); len(panics) != 1 || panics[0]["request_id"] != "abc123" || panics[0]["level"] != "ERROR" {
+		t.Errorf("expected the panic logged with the request ID, got %v", panics)
+	}
+}
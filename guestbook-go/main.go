@@ -17,75 +17,325 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/codegangsta/negroni"
 	"github.com/gorilla/mux"
-	"github.com/xyproto/simpleredis"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var (
-	masterPool *simpleredis.ConnectionPool
-	slavePool  *simpleredis.ConnectionPool
-)
+// store holds the guestbook lists.
+var store Store
+
+// Entry is the JSON representation of a single guestbook entry. Entries
+// written before entries had metadata only have an index, ID and value.
+type Entry struct {
+	Index     int       `json:"index"`
+	ID        string    `json:"id,omitempty"`
+	Value     string    `json:"value"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+	// Status is empty for the entries of a list, see the Entry constants
+	// for entries held back from it.
+	Status string `json:"status,omitempty"`
+	// ClientHash tells apart the clients that wrote entries without
+	// revealing their addresses. It is only served to moderators.
+	ClientHash string `json:"-"`
+}
 
-func ListRangeHandler(rw http.ResponseWriter, req *http.Request) {
+func ListRangeHandler(rw http.ResponseWriter, req *http.Request) error {
 	key := mux.Vars(req)["key"]
-	list := simpleredis.NewList(slavePool, key)
-	members := HandleError(list.GetAll()).([]string)
-	membersJSON := HandleError(json.MarshalIndent(members, "", "  ")).([]byte)
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	page, err := parsePage(req)
+	if err != nil {
+		return err
+	}
+	entries, total, err := store.Range(req.Context(), key, page)
+	if err != nil {
+		return err
+	}
+	return writeMembers(rw, entries, total)
+}
+
+// writeMembers writes the values of entries as the JSON array of strings the
+// legacy routes respond with.
+func writeMembers(rw http.ResponseWriter, entries []Entry, total int) error {
+	members := make([]string, len(entries))
+	for i, entry := range entries {
+		members[i] = entry.Value
+	}
+	membersJSON, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return encodeFailed(err)
+	}
+	setTotalCount(rw, total)
 	rw.Write(membersJSON)
+	return nil
 }
 
-func ListPushHandler(rw http.ResponseWriter, req *http.Request) {
+// newListPushHandler returns a handler that appends the value from the URL
+// path and responds with the whole list. It is kept for old clients; use
+// the handler from newEntryCreateHandler instead.
+func newListPushHandler(entries *entryBuilder) appHandler {
+	return func(rw http.ResponseWriter, req *http.Request) error {
+		key := mux.Vars(req)["key"]
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		entry, err := entries.build(req, mux.Vars(req)["value"], "")
+		if err != nil {
+			return err
+		}
+		page, err := parsePage(req)
+		if err != nil {
+			return err
+		}
+		if _, err := addEntry(req.Context(), key, entry); err != nil {
+			return err
+		}
+		entries, total, err := rangeAfterWrite(req.Context(), key, page)
+		if err != nil {
+			return err
+		}
+		return writeMembers(rw, entries, total)
+	}
+}
+
+func EntryListHandler(rw http.ResponseWriter, req *http.Request) error {
 	key := mux.Vars(req)["key"]
-	value := mux.Vars(req)["value"]
-	list := simpleredis.NewList(masterPool, key)
-	HandleError(nil, list.Add(value))
-	ListRangeHandler(rw, req)
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	page, err := parsePage(req)
+	if err != nil {
+		return err
+	}
+	entries, total, err := store.Range(req.Context(), key, page)
+	if err != nil {
+		return err
+	}
+	setTotalCount(rw, total)
+	return writeJSON(rw, http.StatusOK, entries)
 }
 
-func InfoHandler(rw http.ResponseWriter, req *http.Request) {
-	info := HandleError(masterPool.Get(0).Do("INFO")).([]byte)
-	rw.Write(info)
+// newEntryCreateHandler returns a handler that appends the value of the
+// JSON body, with its optional author, to the list. Entries that await
+// approval are accepted without being added to the list yet.
+func newEntryCreateHandler(entries *entryBuilder) appHandler {
+	return func(rw http.ResponseWriter, req *http.Request) error {
+		key := mux.Vars(req)["key"]
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		var body Entry
+		if err := decodeBody(rw, req, &body); err != nil {
+			return err
+		}
+		entry, err := entries.build(req, body.Value, body.Author)
+		if err != nil {
+			return err
+		}
+
+		entry, err = addEntry(req.Context(), key, entry)
+		if err != nil {
+			return err
+		}
+		if entry.Status == EntryPending {
+			return writeJSON(rw, http.StatusAccepted, entry)
+		}
+
+		rw.Header().Set("Location", fmt.Sprintf("/api/v1/lists/%s/entries/%s", key, url.PathEscape(entry.ID)))
+		return writeJSON(rw, http.StatusCreated, entry)
+	}
 }
 
-func EnvHandler(rw http.ResponseWriter, req *http.Request) {
-	environment := make(map[string]string)
-	for _, item := range os.Environ() {
-		splits := strings.Split(item, "=")
-		key := splits[0]
-		val := strings.Join(splits[1:], "=")
-		environment[key] = val
+// EntryHandler returns the entry with the ID in the path. Entries held back
+// from the list are not found.
+func EntryHandler(rw http.ResponseWriter, req *http.Request) error {
+	vars := mux.Vars(req)
+	key, id := vars["key"], vars["id"]
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	list, _, err := store.Range(req.Context(), key, Page{})
+	if err != nil {
+		return err
+	}
+	i := lastIndexOf(list, id)
+	if i < 0 {
+		return entryNotFound(key, id)
 	}
+	return writeJSON(rw, http.StatusOK, list[i])
+}
 
-	envJSON := HandleError(json.MarshalIndent(environment, "", "  ")).([]byte)
-	rw.Write(envJSON)
+// decodeBody decodes the JSON request body into v, refusing bodies larger
+// than maxBodyBytes.
+func decodeBody(rw http.ResponseWriter, req *http.Request, v interface{}) error {
+	err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxBodyBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeTooLarge, Message: fmt.Sprintf("request body must be at most %d bytes", maxBodyBytes)}
+	} else if err != nil {
+		return badRequest(CodeBadRequest, "invalid JSON body: %v", err)
+	}
+	return nil
 }
 
-func HandleError(result interface{}, err error) (r interface{}) {
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) error {
+	body, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		panic(err)
+		return encodeFailed(err)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	rw.Write(body)
+	return nil
+}
+
+// newRouter returns the guestbook routes, which serve the lists from the
+// package level store and the UI from ui. New entries are made by entries
+// and writes are subject to limiter, which may be nil. Open streams are
+// closed once streamsDone is closed.
+func newRouter(config *Config, entries *entryBuilder, ui *userInterface, limiter *rateLimiter, streamsDone <-chan struct{}) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(tracingMiddleware, metricsMiddleware)
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Path("/lists/{key}/entries").Methods("GET").Handler(appHandler(EntryListHandler))
+	api.Path("/lists/{key}/entries").Methods("POST").Handler(limiter.limit(newEntryCreateHandler(entries)))
+	api.Path("/lists/{key}/entries/{id}").Methods("GET").Handler(appHandler(EntryHandler))
+	api.Path("/guestbooks").Methods("GET").Handler(appHandler(GuestbookListHandler))
+	api.Path("/guestbooks").Methods("POST").Handler(limiter.limit(appHandler(GuestbookCreateHandler)))
+	if config.LegacyRoutes {
+		r.Path("/lrange/{key}").Methods("GET").Handler(appHandler(ListRangeHandler))
+		r.Path("/rpush/{key}/{value}").Methods("GET").Handler(limiter.limit(newListPushHandler(entries)))
 	}
-	return result
+	ui.route(r)
+	r.Path("/stream/{key}").Methods("GET").Handler(newStreamHandler(store, streamsDone))
+	if config.adminCredentials() {
+		admin := api.PathPrefix("/admin").Subrouter()
+		admin.Use(config.adminAuth)
+		// Deleting a guestbook keeps its path, but needs the admin
+		// credentials like the other destructive routes.
+		api.Path("/guestbooks/{name}").Methods("DELETE").Handler(config.adminAuth(appHandler(GuestbookDeleteHandler)))
+		admin.Path("/lists/{key}/held").Methods("GET").Handler(appHandler(HeldListHandler))
+		admin.Path("/lists/{key}/entries/{id}").Methods("DELETE").Handler(appHandler(EntryRemoveHandler))
+		admin.Path("/lists/{key}/entries/{id}/hide").Methods("POST").Handler(appHandler(EntryHideHandler))
+		admin.Path("/lists/{key}/entries/{id}/restore").Methods("POST").Handler(appHandler(EntryRestoreHandler))
+		admin.Path("/audit").Methods("GET").Handler(appHandler(AuditLogHandler))
+		admin.Path("/export").Methods("GET").Handler(appHandler(ExportHandler))
+		admin.Path("/import").Methods("POST").Handler(appHandler(ImportHandler))
+	}
+	if config.Admin {
+		r.Path("/info").Methods("GET").Handler(config.adminAuth(appHandler(InfoHandler)))
+		r.Path("/env").Methods("GET").Handler(config.adminAuth(newEnvHandler(config.redactPatterns())))
+	}
+	r.Path("/healthz").Methods("GET").HandlerFunc(HealthzHandler)
+	r.Path("/readyz").Methods("GET").Handler(newReadyzHandler(store, config.ReadyTimeout))
+	r.Path("/metrics").Methods("GET").Handler(promhttp.Handler())
+
+	return r
 }
 
 func main() {
-	masterPool = simpleredis.NewConnectionPoolHost("redis-master:6379")
-	defer masterPool.Close()
-	slavePool = simpleredis.NewConnectionPoolHost("redis-slave:6379")
-	defer slavePool.Close()
+	var config Config
+	if err := config.AddFlags(flag.CommandLine); err != nil {
+		fatal("invalid environment", err)
+	}
+	flag.Parse()
 
-	r := mux.NewRouter()
-	r.Path("/lrange/{key}").Methods("GET").HandlerFunc(ListRangeHandler)
-	r.Path("/rpush/{key}/{value}").Methods("GET").HandlerFunc(ListPushHandler)
-	r.Path("/info").Methods("GET").HandlerFunc(InfoHandler)
-	r.Path("/env").Methods("GET").HandlerFunc(EnvHandler)
-
-	n := negroni.Classic()
-	n.UseHandler(r)
-	n.Run(":3000")
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		fatal("invalid log level", err)
+	}
+	// This also sends the output of the log package through the logger.
+	slog.SetDefault(newLogger(os.Stderr, level))
+
+	if flag.NArg() > 0 {
+		runCommand(&config, flag.Args())
+		return
+	}
+
+	shutdownTracing, err := config.SetupTracing(context.Background())
+	if err != nil {
+		fatal("setting up tracing failed", err)
+	}
+
+	store, err = config.NewStore()
+	if err != nil {
+		fatal("creating the store failed", err)
+	}
+
+	entries, err := config.newEntryBuilder()
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	ui, err := config.newUI()
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	if err := config.checkAdminCredentials(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	limiter, err := config.NewRateLimiter(store)
+	if err != nil {
+		fatal("creating the rate limiter failed", err)
+	}
+
+	streamsDone := make(chan struct{})
+	r := newRouter(&config, entries, ui, limiter, streamsDone)
+
+	server := &http.Server{
+		Addr:     config.ListenAddr,
+		Handler:  logRequests(recoverPanics(r)),
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	server.RegisterOnShutdown(func() { close(streamsDone) })
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", config.ListenAddr)
+		errc <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case err := <-errc:
+		fatal("server failed", err)
+	case sig := <-signals:
+		slog.Info("draining connections", "signal", sig.String(), "grace_period", config.ShutdownGracePeriod.String())
+	}
+
+	// Shutdown stops accepting new connections and waits for in-flight
+	// requests, so the store must only be closed once it has returned.
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("shutdown did not complete", "error", err.Error())
+	}
+	store.Close()
+
+	// Send the spans of the last requests before exiting.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("flushing traces failed", "error", err.Error())
+	}
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err.Error())
+	os.Exit(1)
 }
@@ -0,0 +1,722 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestServer serves the guestbook routes from s, including the legacy
+// and unauthenticated admin routes.
+func newTestServer(t *testing.T, s Store) *httptest.Server {
+	t.Helper()
+	return newTestServerWithConfig(t, s, &Config{LegacyRoutes: true, Admin: true, ReadyTimeout: time.Second})
+}
+
+func newTestServerWithConfig(t *testing.T, s Store, config *Config) *httptest.Server {
+	t.Helper()
+	store = s
+	limiter, err := config.NewRateLimiter(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamsDone := make(chan struct{})
+	entries, err := config.newEntryBuilder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui, err := config.newUI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newRouter(config, entries, ui, limiter, streamsDone))
+	t.Cleanup(func() {
+		close(streamsDone)
+		server.Close()
+		s.Close()
+	})
+	return server
+}
+
+// newTestRedisStore returns a Redis store whose master and slave are
+// separate in-process fakes. Nothing is replicated between them.
+func newTestRedisStore(t *testing.T) (*redisStore, *miniredis.Miniredis, *miniredis.Miniredis) {
+	t.Helper()
+	master := miniredis.RunT(t)
+	slave := miniredis.RunT(t)
+	var config Config
+	return &redisStore{
+		master: config.NewPool("master", master.Addr()),
+		slave:  config.NewPool("slave", slave.Addr()),
+	}, master, slave
+}
+
+// newTestSharedRedisStore returns a Redis store whose master and slave pools
+// both point at the same fake, as if replication were instantaneous.
+func newTestSharedRedisStore(t *testing.T) (*redisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	var config Config
+	return &redisStore{
+		master: config.NewPool("master", server.Addr()),
+		slave:  config.NewPool("slave", server.Addr()),
+	}, server
+}
+
+// testStores returns each store implementation the handlers should behave
+// the same with.
+func testStores(t *testing.T) map[string]Store {
+	s, _ := newTestSharedRedisStore(t)
+	return map[string]Store{
+		StoreMemory: newMemoryStore(),
+		StoreRedis:  s,
+	}
+}
+
+// withoutMetadata returns entries with only their index and value, for
+// comparing entries whose IDs and creation times are not known in advance.
+func withoutMetadata(entries ...Entry) []Entry {
+	stripped := make([]Entry, len(entries))
+	for i, entry := range entries {
+		stripped[i] = Entry{Index: entry.Index, Value: entry.Value}
+	}
+	return stripped
+}
+
+func do(t *testing.T, method, url, body string, header ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+}
+
+func expectStatus(t *testing.T, resp *http.Response, status int) {
+	t.Helper()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: expected status %d, got %d: %s", resp.Request.Method, resp.Request.URL.Path, status, resp.StatusCode, body)
+	}
+}
+
+// expectError checks that resp is a JSON error response with the given
+// status and code.
+func expectError(t *testing.T, resp *http.Response, status int, code string) ErrorResponse {
+	t.Helper()
+	expectStatus(t, resp, status)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error, got Content-Type %q", ct)
+	}
+	var errResp ErrorResponse
+	decode(t, resp, &errResp)
+	if errResp.Code != code {
+		t.Errorf("expected error code %q, got %q", code, errResp.Code)
+	}
+	if errResp.Message == "" || errResp.RequestID == "" {
+		t.Errorf("expected a message and request ID, got %+v", errResp)
+	}
+	return errResp
+}
+
+func TestEntryAPI(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{AdminToken: "secret"})
+			entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+			var created []Entry
+			for i, value := range []string{"hello", "a/b?c=d&e#f"} {
+				resp := do(t, "POST", entries, `{"value": `+strconv.Quote(value)+`}`)
+				expectStatus(t, resp, http.StatusCreated)
+				var entry Entry
+				decode(t, resp, &entry)
+				loc := resp.Header.Get("Location")
+				if loc != "/api/v1/lists/guestbook/entries/"+entry.ID {
+					t.Errorf("unexpected Location %q", loc)
+				}
+				var found Entry
+				decode(t, do(t, "GET", server.URL+loc, ""), &found)
+				if found != entry {
+					t.Errorf("expected the Location to return %+v, got %+v", entry, found)
+				}
+				if expected := []Entry{{Index: i, Value: value}}; !reflect.DeepEqual(withoutMetadata(entry), expected) {
+					t.Errorf("expected %+v, got %+v", expected, entry)
+				}
+				if entry.ID == "" || time.Since(entry.CreatedAt) > time.Minute {
+					t.Errorf("expected an ID and creation time, got %+v", entry)
+				}
+				created = append(created, entry)
+			}
+
+			resp := do(t, "GET", entries, "")
+			expectStatus(t, resp, http.StatusOK)
+			var list []Entry
+			decode(t, resp, &list)
+			if expected := []Entry{{Index: 0, Value: "hello"}, {Index: 1, Value: "a/b?c=d&e#f"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+				t.Errorf("expected %+v, got %+v", expected, list)
+			}
+
+			expectError(t, do(t, "GET", entries+"/missing", ""), http.StatusNotFound, CodeNotFound)
+			// Entries are only removed by moderators.
+			expectStatus(t, do(t, "DELETE", entries+"/"+created[0].ID, ""), http.StatusNotFound)
+			admin := server.URL + "/api/v1/admin/lists/guestbook/entries/"
+			expectStatus(t, do(t, "DELETE", admin+created[0].ID, "", "Authorization", "Bearer secret"), http.StatusNoContent)
+
+			resp = do(t, "GET", entries, "")
+			decode(t, resp, &list)
+			if expected := []Entry{{Index: 0, Value: "a/b?c=d&e#f"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+				t.Errorf("after delete expected %+v, got %+v", expected, list)
+			}
+		})
+	}
+}
+
+func TestGuestbookAPI(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{AdminToken: "secret"})
+			guestbooks := server.URL + "/api/v1/guestbooks"
+			auth := []string{"Authorization", "Bearer secret"}
+
+			resp := do(t, "POST", guestbooks, `{"name": "party"}`)
+			expectStatus(t, resp, http.StatusCreated)
+			if loc := resp.Header.Get("Location"); loc != "/api/v1/lists/party/entries" {
+				t.Errorf("unexpected Location %q", loc)
+			}
+			expectError(t, do(t, "POST", guestbooks, `{"name": "party"}`), http.StatusConflict, CodeConflict)
+			expectError(t, do(t, "POST", guestbooks, `{"name": "a b"}`), http.StatusBadRequest, CodeBadKey)
+			// Writing to a list makes it a guestbook too.
+			expectStatus(t, do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value": "hello"}`), http.StatusCreated)
+
+			resp = do(t, "GET", guestbooks, "")
+			expectStatus(t, resp, http.StatusOK)
+			var list []Guestbook
+			decode(t, resp, &list)
+			if expected := []Guestbook{{"guestbook", "/g/guestbook"}, {"party", "/g/party"}}; !reflect.DeepEqual(list, expected) {
+				t.Errorf("expected %+v, got %+v", expected, list)
+			}
+
+			expectError(t, do(t, "DELETE", guestbooks+"/guestbook", ""), http.StatusUnauthorized, CodeUnauthorized)
+			expectStatus(t, do(t, "DELETE", guestbooks+"/guestbook", "", auth...), http.StatusNoContent)
+			expectError(t, do(t, "DELETE", guestbooks+"/guestbook", "", auth...), http.StatusNotFound, CodeNotFound)
+			resp = do(t, "GET", guestbooks, "")
+			decode(t, resp, &list)
+			if expected := []Guestbook{{"party", "/g/party"}}; !reflect.DeepEqual(list, expected) {
+				t.Errorf("after delete expected %+v, got %+v", expected, list)
+			}
+			if length, err := s.Len(context.Background(), "guestbook"); err != nil || length != 0 {
+				t.Errorf("expected the entries to be deleted, got length %d, %v", length, err)
+			}
+		})
+	}
+}
+
+func TestGuestbookDeleteNeedsCredentials(t *testing.T) {
+	server := newTestServer(t, newMemoryStore())
+	expectStatus(t, do(t, "POST", server.URL+"/api/v1/guestbooks", `{"name": "party"}`), http.StatusCreated)
+	if resp := do(t, "DELETE", server.URL+"/api/v1/guestbooks/party", ""); resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected no way to delete a guestbook without admin credentials, got status %d", resp.StatusCode)
+	}
+}
+
+func TestGuestbookPage(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{Title: "Our <Guestbook>", ThemeColor: "#123abc", HostInfo: "guestbook-abc12"})
+
+	resp := do(t, "GET", server.URL+"/g/party", "")
+	expectStatus(t, resp, http.StatusOK)
+	body, _ := io.ReadAll(resp.Body)
+	for _, expected := range []string{
+		`<title>party - Our &lt;Guestbook&gt;</title>`,
+		`<body data-guestbook="party" style="--theme-color: #123abc">`,
+		`<h2 id="guestbook-host-address">guestbook-abc12</h2>`,
+		`<script src="/script.js">`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected the page to contain %s, got %s", expected, body)
+		}
+	}
+	if strings.Contains(string(body), `href="/env"`) {
+		t.Errorf("expected no links to the debug pages without -admin, got %s", body)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected the page not to be cached, got Cache-Control %q", cc)
+	}
+
+	resp = do(t, "GET", server.URL+"/", "")
+	expectStatus(t, resp, http.StatusOK)
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), `data-guestbook="guestbook"`) || !strings.Contains(string(body), `<h1>Our &lt;Guestbook&gt;</h1>`) {
+		t.Errorf("expected the default guestbook under the title, got %s", body)
+	}
+	expectError(t, do(t, "GET", server.URL+"/g/a%20b", ""), http.StatusBadRequest, CodeBadKey)
+	expectStatus(t, do(t, "GET", server.URL+"/index.html", ""), http.StatusNotFound)
+
+	if _, err := (&Config{ThemeColor: "red"}).newUI(); err == nil {
+		t.Error("expected a theme color other than #rgb or #rrggbb to be rejected")
+	}
+}
+
+func TestStaticFiles(t *testing.T) {
+	server := newTestServer(t, newMemoryStore())
+
+	resp := do(t, "GET", server.URL+"/style.css", "")
+	expectStatus(t, resp, http.StatusOK)
+	etag := resp.Header.Get("ETag")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected CSS, got Content-Type %q", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); etag == "" || cc != "public, max-age=300" {
+		t.Errorf("expected an ETag and Cache-Control, got %q and %q", etag, cc)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "--theme-color") {
+		t.Errorf("expected the embedded style sheet, got %s", body)
+	}
+
+	expectStatus(t, do(t, "GET", server.URL+"/style.css", "", "If-None-Match", etag), http.StatusNotModified)
+	resp = do(t, "GET", server.URL+"/script.js", "", "If-None-Match", etag)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.Header.Get("ETag") == etag {
+		t.Errorf("expected every file to have its own ETag, got %q twice", etag)
+	}
+}
+
+func TestEntryCreateErrors(t *testing.T) {
+	server := newTestServer(t, newMemoryStore())
+	entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+	expectError(t, do(t, "POST", entries, `{"value": `), http.StatusBadRequest, CodeBadRequest)
+	expectError(t, do(t, "POST", entries, `{"value": ""}`), http.StatusBadRequest, CodeBadRequest)
+	expectError(t, do(t, "POST", server.URL+"/api/v1/lists/"+strings.Repeat("k", maxKeyLength+1)+"/entries", `{"value": "x"}`), http.StatusBadRequest, CodeBadKey)
+	expectError(t, do(t, "POST", server.URL+"/api/v1/lists/a%07b/entries", `{"value": "x"}`), http.StatusBadRequest, CodeBadKey)
+	expectError(t, do(t, "POST", server.URL+"/api/v1/lists/a%20b/entries", `{"value": "x"}`), http.StatusBadRequest, CodeBadKey)
+	expectError(t, do(t, "POST", server.URL+"/api/v1/lists/guestbook:guestbooks/entries", `{"value": "x"}`), http.StatusBadRequest, CodeBadKey)
+	expectError(t, do(t, "POST", entries, `{"value": "  "}`), http.StatusBadRequest, CodeBadRequest)
+	expectError(t, do(t, "POST", entries, `{"value": "a\u0000b"}`), http.StatusBadRequest, CodeBadRequest)
+	expectError(t, do(t, "POST", entries, `{"value": "`+strings.Repeat("x", maxBodyBytes)+`"}`), http.StatusRequestEntityTooLarge, CodeTooLarge)
+}
+
+func TestValueLimits(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{LegacyRoutes: true, MaxValueLength: 5})
+	entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+	expectError(t, do(t, "POST", entries, `{"value": "toolong"}`), http.StatusBadRequest, CodeBadRequest)
+	expectError(t, do(t, "GET", server.URL+"/rpush/guestbook/toolong", ""), http.StatusBadRequest, CodeBadRequest)
+
+	// The limit is in characters, and surrounding white space is dropped.
+	resp := do(t, "POST", entries, `{"value": " héllo "}`)
+	expectStatus(t, resp, http.StatusCreated)
+	var entry Entry
+	decode(t, resp, &entry)
+	if expected := []Entry{{Index: 0, Value: "héllo"}}; !reflect.DeepEqual(withoutMetadata(entry), expected) {
+		t.Errorf("expected %+v, got %+v", expected, entry)
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t, s)
+
+			do(t, "GET", server.URL+"/rpush/guestbook/first", "")
+			resp := do(t, "GET", server.URL+"/rpush/guestbook/second", "")
+			expectStatus(t, resp, http.StatusOK)
+			var values []string
+			decode(t, resp, &values)
+			if expected := []string{"first", "second"}; !reflect.DeepEqual(values, expected) {
+				t.Errorf("rpush: expected %q, got %q", expected, values)
+			}
+
+			resp = do(t, "GET", server.URL+"/lrange/guestbook", "")
+			expectStatus(t, resp, http.StatusOK)
+			decode(t, resp, &values)
+			if expected := []string{"first", "second"}; !reflect.DeepEqual(values, expected) {
+				t.Errorf("lrange: expected %q, got %q", expected, values)
+			}
+		})
+	}
+}
+
+func TestRoutesDisabledByDefault(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{})
+
+	for _, path := range []string{"/lrange/guestbook", "/rpush/guestbook/hello", "/env", "/info"} {
+		if resp := do(t, "GET", server.URL+path, ""); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestPaging(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t, s)
+			for i := 0; i < 10; i++ {
+				s.Append(context.Background(), "guestbook", Entry{Value: strconv.Itoa(i)})
+			}
+
+			tests := []struct {
+				query    string
+				expected []Entry
+			}{
+				{"?limit=2", []Entry{{Index: 0, Value: "0"}, {Index: 1, Value: "1"}}},
+				{"?offset=8&limit=5", []Entry{{Index: 8, Value: "8"}, {Index: 9, Value: "9"}}},
+				{"?offset=10", []Entry{}},
+				{"?order=newest-first&limit=3", []Entry{{Index: 9, Value: "9"}, {Index: 8, Value: "8"}, {Index: 7, Value: "7"}}},
+				{"?order=newest-first&offset=8", []Entry{{Index: 1, Value: "1"}, {Index: 0, Value: "0"}}},
+				{"?order=newest-first&offset=20&limit=1", []Entry{}},
+			}
+			for _, test := range tests {
+				resp := do(t, "GET", server.URL+"/api/v1/lists/guestbook/entries"+test.query, "")
+				expectStatus(t, resp, http.StatusOK)
+				if total := resp.Header.Get("X-Total-Count"); total != "10" {
+					t.Errorf("%s: expected X-Total-Count 10, got %q", test.query, total)
+				}
+				var list []Entry
+				decode(t, resp, &list)
+				if !reflect.DeepEqual(withoutMetadata(list...), test.expected) {
+					t.Errorf("%s: expected %+v, got %+v", test.query, test.expected, list)
+				}
+			}
+
+			for _, query := range []string{"?offset=-1", "?limit=x", "?order=random"} {
+				expectError(t, do(t, "GET", server.URL+"/lrange/guestbook"+query, ""), http.StatusBadRequest, CodeBadRequest)
+			}
+		})
+	}
+}
+
+func TestReplicaSplit(t *testing.T) {
+	s, master, slave := newTestRedisStore(t)
+	server := newTestServer(t, s)
+	entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+	expectStatus(t, do(t, "POST", entries, `{"value": "hello"}`), http.StatusCreated)
+	if values, _ := master.List("guestbook"); len(values) != 1 || decodeEntry(0, values[0]).Value != "hello" {
+		t.Errorf("expected the write on the master, got %q", values)
+	}
+	if slave.Exists("guestbook") {
+		t.Errorf("expected no write on the slave")
+	}
+
+	// Reads must come from the slave, whatever the master holds.
+	slave.RPush("guestbook", "replicated")
+	resp := do(t, "GET", entries, "")
+	var list []Entry
+	decode(t, resp, &list)
+	if expected := []Entry{{Index: 0, Value: "replicated"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+		t.Errorf("expected the read from the slave %+v, got %+v", expected, list)
+	}
+}
+
+func TestReadAfterWrite(t *testing.T) {
+	for _, tc := range []struct {
+		consistency string
+		expected    []string
+	}{
+		{ConsistencyEventual, []string{}},
+		{ConsistencyMaster, []string{"hello"}},
+	} {
+		t.Run(tc.consistency, func(t *testing.T) {
+			s, _, _ := newTestRedisStore(t)
+			s.consistency = tc.consistency
+			server := newTestServer(t, s)
+
+			// The slave never catches up, so only a read from the master
+			// sees the write.
+			resp := do(t, "GET", server.URL+"/rpush/guestbook/hello", "")
+			var values []string
+			decode(t, resp, &values)
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, values)
+			}
+		})
+	}
+}
+
+func TestUnknownConsistency(t *testing.T) {
+	config := Config{Store: StoreRedis, Consistency: "strong"}
+	if _, err := config.NewStore(); err == nil {
+		t.Errorf("expected an error for an unknown consistency")
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	s, _, slave := newTestRedisStore(t)
+	server := newTestServer(t, s)
+	slave.Close()
+
+	expectError(t, do(t, "GET", server.URL+"/lrange/guestbook", ""), http.StatusServiceUnavailable, CodeRedisUnavailable)
+	expectStatus(t, do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value": "hello"}`), http.StatusCreated)
+
+	resp := do(t, "GET", server.URL+"/readyz", "")
+	expectStatus(t, resp, http.StatusServiceUnavailable)
+	var readiness Readiness
+	decode(t, resp, &readiness)
+	if readiness.Status != "unavailable" || readiness.Dependencies["master"].Status != "ok" || readiness.Dependencies["slave"].Status != "unavailable" {
+		t.Errorf("unexpected readiness %+v", readiness)
+	}
+}
+
+func TestSlaveFailover(t *testing.T) {
+	s, _, slave := newTestRedisStore(t)
+	s.breaker = newSlaveBreaker(1, 0)
+	server := newTestServer(t, s)
+	entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+	expectStatus(t, do(t, "POST", entries, `{"value": "hello"}`), http.StatusCreated)
+	slave.Close()
+	var list []Entry
+	decode(t, do(t, "GET", entries, ""), &list)
+	if expected := []Entry{{Index: 0, Value: "hello"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+		t.Errorf("expected the read from the master %+v, got %+v", expected, list)
+	}
+	if s.breaker.openedAt.IsZero() {
+		t.Errorf("expected reads to have switched to the master")
+	}
+	resp := do(t, "GET", server.URL+"/readyz", "")
+	expectStatus(t, resp, http.StatusOK)
+	var readiness Readiness
+	decode(t, resp, &readiness)
+	if readiness.Status != "degraded" || readiness.Dependencies["slave"].Status != "unavailable" {
+		t.Errorf("expected the slave to be reported without failing readiness, got %+v", readiness)
+	}
+
+	// With no cooldown the next read tries the slave again.
+	if err := slave.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	slave.RPush("guestbook", "replicated")
+	list = nil
+	decode(t, do(t, "GET", entries, ""), &list)
+	if expected := []Entry{{Index: 0, Value: "replicated"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+		t.Errorf("expected the read from the slave %+v, got %+v", expected, list)
+	}
+	if !s.breaker.openedAt.IsZero() {
+		t.Errorf("expected reads to have switched back to the slave")
+	}
+}
+
+func TestWrongType(t *testing.T) {
+	s, server := newTestSharedRedisStore(t)
+	server.Set("guestbook", "not a list")
+	ts := newTestServer(t, s)
+
+	expectError(t, do(t, "GET", ts.URL+"/lrange/guestbook", ""), http.StatusBadRequest, CodeBadKey)
+	expectError(t, do(t, "POST", ts.URL+"/api/v1/lists/guestbook/entries", `{"value": "x"}`), http.StatusBadRequest, CodeBadKey)
+}
+
+func TestErrorRequestID(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{AdminToken: "secret"})
+	resp := do(t, "DELETE", server.URL+"/api/v1/admin/lists/guestbook/entries/missing", "", "X-Request-ID", "abc123", "Authorization", "Bearer secret")
+	errResp := expectError(t, resp, http.StatusNotFound, CodeNotFound)
+	if errResp.RequestID != "abc123" || resp.Header.Get("X-Request-ID") != "abc123" {
+		t.Errorf("expected the request ID to be echoed, got %q and header %q", errResp.RequestID, resp.Header.Get("X-Request-ID"))
+	}
+}
+
+func TestInfoHandler(t *testing.T) {
+	s, _ := newTestSharedRedisStore(t)
+	server := newTestServer(t, s)
+
+	resp := do(t, "GET", server.URL+"/info", "")
+	expectStatus(t, resp, http.StatusOK)
+	var info InfoResponse
+	decode(t, resp, &info)
+	if info.Servers["master"].Sections["clients"]["connected_clients"] == "" {
+		t.Errorf("expected the master's clients section, got %+v", info.Servers["master"])
+	}
+	// The fake does not implement INFO replication, which is reported
+	// without failing the whole request.
+	if info.Servers["slave"].Error == "" {
+		t.Errorf("expected an error for the slave, got %+v", info.Servers["slave"])
+	}
+
+	resp = do(t, "GET", server.URL+"/info?format=text", "")
+	expectStatus(t, resp, http.StatusOK)
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(string(body), "# guestbook:master\r\n# Clients") {
+		t.Errorf("expected INFO text, got %q", body)
+	}
+}
+
+func TestEnvHandler(t *testing.T) {
+	t.Setenv("GUESTBOOK_TEST", "a=b")
+	t.Setenv("GUESTBOOK_DB_PASSWORD", "hunter2")
+	t.Setenv("guestbook_token", "abc")
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{Admin: true, EnvRedact: "*PASSWORD*, *TOKEN*"})
+	resp := do(t, "GET", server.URL+"/env", "")
+	expectStatus(t, resp, http.StatusOK)
+	var env map[string]string
+	decode(t, resp, &env)
+	expected := map[string]string{
+		"GUESTBOOK_TEST":        "a=b",
+		"GUESTBOOK_DB_PASSWORD": redactedValue,
+		"guestbook_token":       redactedValue,
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("expected %s=%q, got %q", name, value, env[name])
+		}
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		header   []string
+		expected int
+	}{
+		{"open", Config{}, nil, http.StatusOK},
+		{"no token", Config{AdminToken: "s3cret"}, nil, http.StatusUnauthorized},
+		{"wrong token", Config{AdminToken: "s3cret"}, []string{"Authorization", "Bearer guess"}, http.StatusUnauthorized},
+		{"token", Config{AdminToken: "s3cret"}, []string{"Authorization", "Bearer s3cret"}, http.StatusOK},
+		{"no basic auth", Config{AdminUser: "admin", AdminPassword: "pw"}, nil, http.StatusUnauthorized},
+		{"wrong password", Config{AdminUser: "admin", AdminPassword: "pw"}, []string{"Authorization", "Basic YWRtaW46d3Jvbmc="}, http.StatusUnauthorized},
+		{"basic auth", Config{AdminUser: "admin", AdminPassword: "pw"}, []string{"Authorization", "Basic YWRtaW46cHc="}, http.StatusOK},
+		{"either", Config{AdminToken: "s3cret", AdminUser: "admin", AdminPassword: "pw"}, []string{"Authorization", "Basic YWRtaW46cHc="}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Admin = true
+			server := newTestServerWithConfig(t, newMemoryStore(), &config)
+			for _, path := range []string{"/env", "/info"} {
+				resp := do(t, "GET", server.URL+path, "", test.header...)
+				if test.expected == http.StatusUnauthorized {
+					expectError(t, resp, http.StatusUnauthorized, CodeUnauthorized)
+					if resp.Header.Get("WWW-Authenticate") == "" {
+						t.Errorf("GET %s: expected a WWW-Authenticate challenge", path)
+					}
+				} else {
+					expectStatus(t, resp, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminCredentials(t *testing.T) {
+	for _, config := range []Config{{AdminUser: "admin"}, {AdminPassword: "pw"}} {
+		if err := config.checkAdminCredentials(); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+	for _, config := range []Config{{}, {AdminToken: "s3cret"}, {AdminUser: "admin", AdminPassword: "pw"}} {
+		if err := config.checkAdminCredentials(); err != nil {
+			t.Errorf("expected %+v to be accepted, got %v", config, err)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s, _ := newTestSharedRedisStore(t)
+	server := newTestServer(t, s)
+
+	expectStatus(t, do(t, "GET", server.URL+"/healthz", ""), http.StatusOK)
+	resp := do(t, "GET", server.URL+"/readyz", "")
+	expectStatus(t, resp, http.StatusOK)
+	var readiness Readiness
+	decode(t, resp, &readiness)
+	if len(readiness.Dependencies) != 2 {
+		t.Errorf("expected master and slave dependencies, got %+v", readiness.Dependencies)
+	}
+}
+
+func TestStream(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t, s)
+			first, _ := s.Append(context.Background(), "guestbook", Entry{Value: "first"})
+			before, _ := s.Append(context.Background(), "guestbook", Entry{Value: "before"})
+
+			// Entries after the last one seen are replayed.
+			resp := do(t, "GET", server.URL+"/stream/guestbook", "", "Last-Event-ID", first.ID)
+			expectStatus(t, resp, http.StatusOK)
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("unexpected Content-Type %q", ct)
+			}
+			events := bufio.NewReader(resp.Body)
+			expectEvent(t, events, before)
+
+			after, _ := s.Append(context.Background(), "guestbook", Entry{Value: "after"})
+			expectEvent(t, events, after)
+			resp.Body.Close()
+
+			// A client whose last entry is gone is told to start over.
+			resp = do(t, "GET", server.URL+"/stream/guestbook", "", "Last-Event-ID", "removed")
+			events = bufio.NewReader(resp.Body)
+			if lines := readEvent(t, events); !reflect.DeepEqual(lines, []string{"event: reset\n", "data: {}\n"}) {
+				t.Errorf("expected a reset event, got %q", lines)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+// readEvent returns the lines of the next event on the stream.
+func readEvent(t *testing.T, events *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		if line == "\n" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func expectEvent(t *testing.T, events *bufio.Reader, expected Entry) {
+	t.Helper()
+	lines := readEvent(t, events)
+	data, _ := json.Marshal(expected)
+	want := []string{"id: " + expected.ID + "\n", "event: entry\n", "data: " + string(data) + "\n"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected event %q, got %q", want, lines)
+	}
+}
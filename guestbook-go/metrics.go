@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guestbook_http_requests_total",
+		Help: "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "guestbook_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "guestbook_redis_command_duration_seconds",
+		Help:    "Latency of Redis commands by pool and command.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"pool", "command"})
+	redisErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guestbook_redis_command_errors_total",
+		Help: "Number of failed Redis commands by pool and command.",
+	}, []string{"pool", "command"})
+
+	readPool = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "guestbook_redis_read_pool",
+		Help: "1 for the Redis pool reads are currently sent to, 0 for the other.",
+	}, []string{"pool"})
+	slaveFailovers = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "guestbook_redis_slave_failovers_total",
+		Help: "Number of times reads were switched to the master because the slaves were unavailable.",
+	})
+
+	rateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "guestbook_rate_limited_requests_total",
+		Help: "Number of writes rejected because the client exceeded its rate limit.",
+	})
+
+	replicationWaitTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "guestbook_replication_wait_timeouts_total",
+		Help: "Number of writes not acknowledged by enough slaves within the consistency timeout.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpDuration, redisDuration, redisErrors, readPool, slaveFailovers, replicationWaitTimeouts, rateLimited)
+}
+
+// metricsMiddleware is a mux middleware that records every matched request
+// under its route template, e.g. "/lrange/{key}", so that the number of
+// series does not grow with the number of lists.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		route := routeTemplate(req)
+		setRoute(req, route)
+		start := time.Now()
+		nrw := negroni.NewResponseWriter(rw)
+		next.ServeHTTP(nrw, req)
+
+		httpDuration.WithLabelValues(route, req.Method).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, req.Method, strconv.Itoa(nrw.Status())).Inc()
+	})
+}
+
+// routeTemplate returns the template of the route that req matched.
+func routeTemplate(req *http.Request) string {
+	if tmpl, err := mux.CurrentRoute(req).GetPathTemplate(); err == nil {
+		return tmpl
+	}
+	return "unknown"
+}
+
+// instrumentedConn records the latency and errors of every command sent on
+// a connection belonging to the named pool.
+type instrumentedConn struct {
+	redis.Conn
+	pool string
+}
+
+func (c instrumentedConn) Do(command string, args ...interface{}) (interface{}, error) {
+	if command == "" {
+		// The pool flushes pending commands with an empty Do.
+		return c.Conn.Do(command, args...)
+	}
+	start := time.Now()
+	reply, err := c.Conn.Do(command, args...)
+	c.observe(command, start, err)
+	return reply, err
+}
+
+func (c instrumentedConn) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	if command == "" {
+		return redis.DoWithTimeout(c.Conn, timeout, command, args...)
+	}
+	start := time.Now()
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, command, args...)
+	c.observe(command, start, err)
+	return reply, err
+}
+
+func (c instrumentedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c instrumentedConn) observe(command string, start time.Time, err error) {
+	command = strings.ToUpper(command)
+	redisDuration.WithLabelValues(c.pool, command).Observe(time.Since(start).Seconds())
+	if err != nil {
+		redisErrors.WithLabelValues(c.pool, command).Inc()
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Statuses of the entries held back from a list.
+const (
+	// EntryHidden is an entry a moderator has hidden.
+	EntryHidden = "hidden"
+	// EntryPending is a new entry that awaits approval with -moderate.
+	EntryPending = "pending"
+)
+
+// Moderation actions recorded in the audit log.
+const (
+	ActionHide    = "hide"
+	ActionRestore = "restore"
+	ActionApprove = "approve"
+	ActionDelete  = "delete"
+)
+
+// maxAuditRecords is how many moderation actions the audit log keeps.
+const maxAuditRecords = 10000
+
+// AuditRecord is a moderation action in the audit log.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Key       string    `json:"key"`
+	EntryID   string    `json:"entryId"`
+	Admin     string    `json:"admin"`
+	RequestID string    `json:"requestId"`
+}
+
+// HeldEntry is an entry as shown to moderators, who also see the hash of
+// the client that wrote it.
+type HeldEntry struct {
+	ID         string    `json:"id"`
+	Value      string    `json:"value"`
+	Author     string    `json:"author,omitempty"`
+	CreatedAt  time.Time `json:"createdAt,omitzero"`
+	Status     string    `json:"status,omitempty"`
+	ClientHash string    `json:"clientHash,omitempty"`
+}
+
+func newHeldEntry(entry Entry) HeldEntry {
+	return HeldEntry{
+		ID:         entry.ID,
+		Value:      entry.Value,
+		Author:     entry.Author,
+		CreatedAt:  entry.CreatedAt,
+		Status:     entry.Status,
+		ClientHash: entry.ClientHash,
+	}
+}
+
+func sortByCreation(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+}
+
+// addEntry appends entry to the list, or holds it back if it awaits
+// approval.
+func addEntry(ctx context.Context, key string, entry Entry) (Entry, error) {
+	if entry.Status == EntryPending {
+		return store.Hold(ctx, key, entry)
+	}
+	return store.Append(ctx, key, entry)
+}
+
+// moderatedEntry returns the list and entry ID from the path of req.
+func moderatedEntry(req *http.Request) (key, id string, err error) {
+	vars := mux.Vars(req)
+	if err := validateKey(vars["key"]); err != nil {
+		return "", "", err
+	}
+	return vars["key"], vars["id"], nil
+}
+
+func entryNotFound(key, id string) *Error {
+	return notFound("entry %q not found in %q", id, key)
+}
+
+func HeldListHandler(rw http.ResponseWriter, req *http.Request) error {
+	key := mux.Vars(req)["key"]
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	entries, err := store.Held(req.Context(), key)
+	if err != nil {
+		return err
+	}
+	held := make([]HeldEntry, len(entries))
+	for i, entry := range entries {
+		held[i] = newHeldEntry(entry)
+	}
+	return writeJSON(rw, http.StatusOK, held)
+}
+
+// EntryHideHandler moves an entry from the list to the held entries. Should
+// holding it fail, the entry is put back at the end of the list rather than
+// lost.
+func EntryHideHandler(rw http.ResponseWriter, req *http.Request) error {
+	key, id, err := moderatedEntry(req)
+	if err != nil {
+		return err
+	}
+	entry, err := store.Remove(req.Context(), key, id)
+	if err == ErrNotFound {
+		return entryNotFound(key, id)
+	} else if err != nil {
+		return err
+	}
+
+	hidden := entry
+	hidden.Status = EntryHidden
+	if hidden, err = store.Hold(req.Context(), key, hidden); err != nil {
+		if _, appendErr := store.Append(req.Context(), key, entry); appendErr != nil {
+			requestLogger(req).Error("entry lost while hiding it", "key", key, "id", entry.ID, "value", entry.Value, "error", appendErr.Error())
+		}
+		return err
+	}
+	audit(req, ActionHide, key, id)
+	return writeJSON(rw, http.StatusOK, newHeldEntry(hidden))
+}
+
+// EntryRestoreHandler appends a hidden entry back to the end of its list,
+// or approves a pending one.
+func EntryRestoreHandler(rw http.ResponseWriter, req *http.Request) error {
+	key, id, err := moderatedEntry(req)
+	if err != nil {
+		return err
+	}
+	held, err := store.Release(req.Context(), key, id)
+	if err == ErrNotFound {
+		return entryNotFound(key, id)
+	} else if err != nil {
+		return err
+	}
+
+	entry := held
+	entry.Status = ""
+	if entry, err = store.Append(req.Context(), key, entry); err != nil {
+		if _, holdErr := store.Hold(req.Context(), key, held); holdErr != nil {
+			requestLogger(req).Error("entry lost while restoring it", "key", key, "id", held.ID, "value", held.Value, "error", holdErr.Error())
+		}
+		return err
+	}
+	action := ActionRestore
+	if held.Status == EntryPending {
+		action = ActionApprove
+	}
+	audit(req, action, key, id)
+	return writeJSON(rw, http.StatusOK, entry)
+}
+
+// EntryRemoveHandler deletes an entry by ID, whether it is in the list or
+// held back from it.
+func EntryRemoveHandler(rw http.ResponseWriter, req *http.Request) error {
+	key, id, err := moderatedEntry(req)
+	if err != nil {
+		return err
+	}
+	_, err = store.Remove(req.Context(), key, id)
+	if err == ErrNotFound {
+		_, err = store.Release(req.Context(), key, id)
+	}
+	if err == ErrNotFound {
+		return entryNotFound(key, id)
+	} else if err != nil {
+		return err
+	}
+	audit(req, ActionDelete, key, id)
+	rw.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// AuditLogHandler returns the latest moderation actions, up to the limit
+// query parameter.
+func AuditLogHandler(rw http.ResponseWriter, req *http.Request) error {
+	limit := 100
+	if v := req.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxAuditRecords {
+			return badRequest(CodeBadRequest, "limit must be between 1 and %d", maxAuditRecords)
+		}
+	}
+	records, err := store.AuditLog(req.Context(), limit)
+	if err != nil {
+		return err
+	}
+	return writeJSON(rw, http.StatusOK, records)
+}
+
+// audit records a moderation action that has been carried out. Failing to
+// do so is logged as an error, but does not fail the request since the
+// action cannot be undone any more.
+func audit(req *http.Request, action, key, id string) {
+	record := AuditRecord{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Key:       key,
+		EntryID:   id,
+		Admin:     adminName(req),
+		RequestID: requestID(req),
+	}
+	if err := store.Audit(req.Context(), record); err != nil {
+		requestLogger(req).Error("recording moderation action failed", "action", action, "key", key, "id", id, "error", err.Error())
+	}
+}
+
+// adminName returns who made an admin request: the basic auth user, or
+// "token" for the bearer token.
+func adminName(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok {
+		return user
+	}
+	if strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+		return "token"
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// listValues returns the values of the public list.
+func listValues(t *testing.T, server string) []string {
+	t.Helper()
+	var list []Entry
+	decode(t, do(t, "GET", server+"/api/v1/lists/guestbook/entries", ""), &list)
+	values := []string{}
+	for _, entry := range list {
+		values = append(values, entry.Value)
+	}
+	return values
+}
+
+func TestModeration(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{AdminUser: "mod", AdminPassword: "secret"})
+			entries := server.URL + "/api/v1/lists/guestbook/entries"
+			admin := server.URL + "/api/v1/admin/lists/guestbook"
+			auth := []string{"Authorization", "Basic bW9kOnNlY3JldA=="}
+
+			var ids []string
+			for _, value := range []string{"nice", "abusive", "spam"} {
+				resp := do(t, "POST", entries, `{"value": "`+value+`"}`)
+				expectStatus(t, resp, http.StatusCreated)
+				var entry Entry
+				decode(t, resp, &entry)
+				ids = append(ids, entry.ID)
+			}
+
+			expectError(t, do(t, "POST", admin+"/entries/"+ids[1]+"/hide", ""), http.StatusUnauthorized, CodeUnauthorized)
+			resp := do(t, "POST", admin+"/entries/"+ids[1]+"/hide", "", auth...)
+			expectStatus(t, resp, http.StatusOK)
+			var hidden HeldEntry
+			decode(t, resp, &hidden)
+			if hidden.ID != ids[1] || hidden.Status != EntryHidden || hidden.ClientHash == "" {
+				t.Errorf("expected the hidden entry with its client hash, got %+v", hidden)
+			}
+			expectError(t, do(t, "POST", admin+"/entries/"+ids[1]+"/hide", "", auth...), http.StatusNotFound, CodeNotFound)
+			if values := listValues(t, server.URL); !reflect.DeepEqual(values, []string{"nice", "spam"}) {
+				t.Errorf("expected the hidden entry to be left out, got %q", values)
+			}
+
+			var held []HeldEntry
+			decode(t, do(t, "GET", admin+"/held", "", auth...), &held)
+			if len(held) != 1 || held[0].Value != "abusive" {
+				t.Errorf("expected the hidden entry to be held, got %+v", held)
+			}
+
+			expectStatus(t, do(t, "DELETE", admin+"/entries/"+ids[2], "", auth...), http.StatusNoContent)
+			expectStatus(t, do(t, "POST", admin+"/entries/"+ids[1]+"/restore", "", auth...), http.StatusOK)
+			if values := listValues(t, server.URL); !reflect.DeepEqual(values, []string{"nice", "abusive"}) {
+				t.Errorf("expected the restored entry at the end, got %q", values)
+			}
+			expectError(t, do(t, "DELETE", admin+"/entries/"+ids[2], "", auth...), http.StatusNotFound, CodeNotFound)
+
+			var records []AuditRecord
+			decode(t, do(t, "GET", server.URL+"/api/v1/admin/audit", "", auth...), &records)
+			var actions []string
+			for _, record := range records {
+				if record.Admin != "mod" || record.Key != "guestbook" || record.RequestID == "" {
+					t.Errorf("unexpected audit record %+v", record)
+				}
+				actions = append(actions, record.Action+" "+record.EntryID)
+			}
+			if expected := []string{"restore " + ids[1], "delete " + ids[2], "hide " + ids[1]}; !reflect.DeepEqual(actions, expected) {
+				t.Errorf("expected the audit log %q, got %q", expected, actions)
+			}
+		})
+	}
+}
+
+func TestModerateNewEntries(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{Moderate: true, LegacyRoutes: true, AdminToken: "secret"})
+			auth := []string{"Authorization", "Bearer secret"}
+
+			resp := do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value": "hello"}`)
+			expectStatus(t, resp, http.StatusAccepted)
+			var pending Entry
+			decode(t, resp, &pending)
+			if pending.Status != EntryPending || pending.ID == "" {
+				t.Errorf("expected a pending entry, got %+v", pending)
+			}
+			expectStatus(t, do(t, "GET", server.URL+"/rpush/guestbook/legacy", ""), http.StatusOK)
+			if values := listValues(t, server.URL); len(values) != 0 {
+				t.Errorf("expected no entries before approval, got %q", values)
+			}
+
+			expectStatus(t, do(t, "POST", server.URL+"/api/v1/admin/lists/guestbook/entries/"+pending.ID+"/restore", "", auth...), http.StatusOK)
+			if values := listValues(t, server.URL); !reflect.DeepEqual(values, []string{"hello"}) {
+				t.Errorf("expected the approved entry, got %q", values)
+			}
+			var records []AuditRecord
+			decode(t, do(t, "GET", server.URL+"/api/v1/admin/audit?limit=1", "", auth...), &records)
+			if len(records) != 1 || records[0].Action != ActionApprove || records[0].Admin != "token" {
+				t.Errorf("expected the approval to be audited, got %+v", records)
+			}
+			var raw []map[string]interface{}
+			decode(t, do(t, "GET", server.URL+"/api/v1/admin/audit?limit=1", "", auth...), &raw)
+			if len(raw) != 1 || raw[0]["entryId"] != pending.ID {
+				t.Errorf("expected the entry ID under entryId, got %v", raw)
+			}
+		})
+	}
+}
+
+func TestModerationBareEntries(t *testing.T) {
+	s, server := newTestSharedRedisStore(t)
+	server.RPush("guestbook", "written before records")
+	httpServer := newTestServerWithConfig(t, s, &Config{AdminToken: "secret"})
+
+	id := bareEntryID("written before records")
+	expectStatus(t, do(t, "POST", httpServer.URL+"/api/v1/admin/lists/guestbook/entries/"+id+"/hide", "", "Authorization", "Bearer secret"), http.StatusOK)
+	if values := listValues(t, httpServer.URL); len(values) != 0 {
+		t.Errorf("expected the bare entry to be hidden, got %q", values)
+	}
+}
+
+func TestModerationRoutesNeedCredentials(t *testing.T) {
+	server := newTestServerWithConfig(t, newMemoryStore(), &Config{Admin: true})
+	expectStatus(t, do(t, "GET", server.URL+"/api/v1/admin/audit", ""), http.StatusNotFound)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Values of the order query parameter.
+const (
+	OrderOldestFirst = "oldest-first"
+	OrderNewestFirst = "newest-first"
+)
+
+// Page selects part of a list. A zero Limit means no limit, so a zero Page
+// is the whole list, oldest entry first.
+type Page struct {
+	Offset      int
+	Limit       int
+	NewestFirst bool
+}
+
+// parsePage reads a Page from the offset, limit and order query parameters.
+func parsePage(req *http.Request) (Page, error) {
+	var page Page
+	query := req.URL.Query()
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return Page{}, badRequest(CodeBadRequest, "offset must be a non-negative integer")
+		}
+		page.Offset = offset
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return Page{}, badRequest(CodeBadRequest, "limit must be a non-negative integer")
+		}
+		page.Limit = limit
+	}
+	switch order := query.Get("order"); order {
+	case "", OrderOldestFirst:
+	case OrderNewestFirst:
+		page.NewestFirst = true
+	default:
+		return Page{}, badRequest(CodeBadRequest, "order must be %q or %q", OrderOldestFirst, OrderNewestFirst)
+	}
+	return page, nil
+}
+
+// bounds returns the LRANGE start and stop for the page. Newest first pages
+// count back from the end of the list with negative indexes.
+func (p Page) bounds() (start, stop int) {
+	if p.NewestFirst {
+		start, stop = 0, -(p.Offset + 1)
+		if p.Limit > 0 {
+			start = -(p.Offset + p.Limit)
+		}
+		return start, stop
+	}
+	start, stop = p.Offset, -1
+	if p.Limit > 0 {
+		stop = p.Offset + p.Limit - 1
+	}
+	return start, stop
+}
+
+// entries decodes and numbers the values LRANGE returned for the page of a
+// list of the given length, reversing them for a newest first page.
+func (p Page) entries(values []string, total int, decode func(index int, value string) Entry) []Entry {
+	entries := make([]Entry, len(values))
+	for i, value := range values {
+		if p.NewestFirst {
+			index := len(values) - 1 - i
+			entries[index] = decode(total-1-p.Offset-index, value)
+		} else {
+			entries[i] = decode(p.Offset+i, value)
+		}
+	}
+	return entries
+}
+
+// setTotalCount reports the length of the whole list to paging clients.
+func setTotalCount(rw http.ResponseWriter, total int) {
+	rw.Header().Set("X-Total-Count", strconv.Itoa(total))
+}
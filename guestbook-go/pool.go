@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Ways of finding the Redis servers accepted by the -redis-mode flag.
+const (
+	// RedisDirect uses the fixed master and slave addresses.
+	RedisDirect = "direct"
+	// RedisSentinel asks Redis Sentinel for the current master and slaves.
+	RedisSentinel = "sentinel"
+	// RedisCluster spreads the lists over the masters of a Redis Cluster
+	// and reads them from their replicas.
+	RedisCluster = "cluster"
+)
+
+// errNoServer is returned when there is currently no server to connect to,
+// such as while Sentinel knows of no healthy slave.
+var errNoServer = errors.New("no redis server available")
+
+// redisPool hands out connections to either the master or the slaves.
+type redisPool interface {
+	// Get returns a pooled connection for commands on key, or on no key in
+	// particular if key is empty. Commands on it are traced as part of ctx.
+	Get(ctx context.Context, key string) redis.Conn
+	// Dial returns a new connection that does not go back to the pool, as
+	// needed for a subscription.
+	Dial() (redis.Conn, error)
+	// EachNode calls fn with a connection to every server that holds a
+	// share of the keys, as commands such as SCAN only see the keys of the
+	// server they are sent to.
+	EachNode(ctx context.Context, fn func(conn redis.Conn) error) error
+	Ping() error
+	Close() error
+}
+
+// NewPools returns the master and slave pools for the configured Redis mode.
+func (c *Config) NewPools() (master, slave redisPool, err error) {
+	switch c.RedisMode {
+	case RedisDirect:
+		return c.NewPool("master", c.MasterAddr()), c.NewPool("slave", c.SlaveAddr()), nil
+	case RedisSentinel:
+		s := c.newSentinel()
+		return c.newSentinelPool("master", s.masterAddr), c.newSentinelPool("slave", s.slaveAddr), nil
+	case RedisCluster:
+		return c.newClusterPools()
+	}
+	return nil, nil, fmt.Errorf("unknown redis mode %q, must be %q, %q or %q", c.RedisMode, RedisDirect, RedisSentinel, RedisCluster)
+}
+
+// connPool is a redisPool whose connections all go to interchangeable
+// servers.
+type connPool struct {
+	*redis.Pool
+	name string
+}
+
+func (p connPool) Get(ctx context.Context, key string) redis.Conn {
+	return traceConn(ctx, p.name, p.Pool.Get())
+}
+
+func (p connPool) Dial() (redis.Conn, error) {
+	return p.Pool.Dial()
+}
+
+func (p connPool) EachNode(ctx context.Context, fn func(conn redis.Conn) error) error {
+	conn := p.Get(ctx, "")
+	defer conn.Close()
+	return fn(conn)
+}
+
+func (p connPool) Ping() error {
+	return ping(p.Pool.Get())
+}
+
+func ping(conn redis.Conn) error {
+	defer conn.Close()
+	_, err := conn.Do("PING")
+	return err
+}
@@ -1,46 +1,131 @@
 $(document).ready(function() {
-  var headerTitleElement = $("#header h1");
   var entriesElement = $("#guestbook-entries");
   var formElement = $("#guestbook-form");
   var submitElement = $("#guestbook-submit");
   var entryContentElement = $("#guestbook-entry-content");
-  var hostAddressElement = $("#guestbook-host-address");
+  var entryAuthorElement = $("#guestbook-entry-author");
+  var messageElement = $("#guestbook-message");
+
+  // The server renders the page with the guestbook it shows.
+  var guestbook = $("body").attr("data-guestbook");
+  var entriesURL = "/api/v1/lists/" + encodeURIComponent(guestbook) + "/entries";
+
+  // IDs of the entries shown, by index. A streamed entry that a full refresh
+  // has already displayed has the ID of the entry at its index; the index
+  // alone cannot tell since indexes shift when entries are trimmed or
+  // removed.
+  var shownIDs = [];
+
+  // Entries written before entries had metadata have no author or time.
+  var renderEntry = function(entry) {
+    var element = $("<p>").text(entry.value);
+    var meta = [];
+    if (entry.author) {
+      meta.push(entry.author);
+    }
+    if (entry.createdAt) {
+      meta.push(new Date(entry.createdAt).toLocaleString());
+    }
+    if (meta.length > 0) {
+      element.append($("<span>").addClass("entry-meta").text(meta.join(", ")));
+    }
+    return element;
+  }
 
   var appendGuestbookEntries = function(data) {
     entriesElement.empty();
-    $.each(data, function(key, val) {
-      entriesElement.append("<p>" + val + "</p>");
+    shownIDs = [];
+    $.each(data, function(key, entry) {
+      entriesElement.append(renderEntry(entry));
+      shownIDs.push(entry.id);
     });
   }
 
+  var refreshGuestbook = function() {
+    $.getJSON(entriesURL).done(appendGuestbookEntries);
+  }
+
+  // An entry that does not directly follow the shown ones means that some
+  // were missed, or that the list was trimmed or changed by a moderator, so
+  // the whole list is read again.
+  var showEntry = function(entry) {
+    if (shownIDs[entry.index] == entry.id) {
+      return;
+    }
+    if (entry.index == shownIDs.length) {
+      entriesElement.append(renderEntry(entry));
+      shownIDs.push(entry.id);
+    } else {
+      refreshGuestbook();
+    }
+  }
+
+  var appendStreamedEntry = function(e) {
+    showEntry(JSON.parse(e.data));
+  }
+
+  // The created entry is shown as the server returned it rather than read
+  // back, since reads may go to a slave that has not seen it yet. The input
+  // is kept when the entry is rejected, so that it can be corrected.
   var handleSubmission = function(e) {
     e.preventDefault();
     var entryValue = entryContentElement.val()
     if (entryValue.length > 0) {
-      entriesElement.append("<p>...</p>");
-      $.getJSON("rpush/guestbook/" + entryValue, appendGuestbookEntries);
+      var pendingElement = $("<p>...</p>");
+      entriesElement.append(pendingElement);
+      messageElement.text("");
+      $.ajax({
+        url: entriesURL,
+        method: "POST",
+        contentType: "application/json",
+        data: JSON.stringify({value: entryValue, author: entryAuthorElement.val()})
+      }).done(function(entry, status, xhr) {
+        entryContentElement.val("");
+        if (xhr.status == 202) {
+          messageElement.text("Your entry is shown once it has been approved.");
+        } else {
+          showEntry(entry);
+        }
+      }).fail(function(xhr) {
+        var error = xhr.responseJSON;
+        messageElement.text(error && error.message ? error.message : "Your entry could not be saved, please try again.");
+      }).always(function() {
+        pendingElement.remove();
+      });
     }
     return false;
   }
 
-  // colors = purple, blue, red, green, yellow
-  var colors = ["#549", "#18d", "#d31", "#2a4", "#db1"];
-  var randomColor = colors[Math.floor(5 * Math.random())];
-  (function setElementsColor(color) {
-    headerTitleElement.css("color", color);
-    entryContentElement.css("box-shadow", "inset 0 0 0 2px " + color);
-    submitElement.css("background-color", color);
-  })(randomColor);
-
   submitElement.click(handleSubmission);
   formElement.submit(handleSubmission);
-  hostAddressElement.append(document.URL);
 
-  // Poll every second.
-  (function fetchGuestbook() {
-    $.getJSON("lrange/guestbook").done(appendGuestbookEntries).always(
+  // Poll every second while the stream is not connected.
+  var polling = false;
+  var fetchGuestbook = function() {
+    $.getJSON(entriesURL).done(appendGuestbookEntries).always(
       function() {
-        setTimeout(fetchGuestbook, 1000);
+        if (polling) {
+          setTimeout(fetchGuestbook, 1000);
+        }
       });
-  })();
+  }
+  var startPolling = function() {
+    if (!polling) {
+      polling = true;
+      fetchGuestbook();
+    }
+  }
+
+  if (window.EventSource) {
+    var source = new EventSource("/stream/" + encodeURIComponent(guestbook));
+    source.addEventListener("entry", appendStreamedEntry);
+    source.addEventListener("reset", refreshGuestbook);
+    source.onopen = function() {
+      polling = false;
+      fetchGuestbook();
+    };
+    source.onerror = startPolling;
+  } else {
+    startPolling();
+  }
 });
@@ -0,0 +1,267 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// CodeRateLimited is the error code of a write rejected by the rate limit.
+const CodeRateLimited = "rate_limited"
+
+// tokenBuckets holds a token bucket per client.
+type tokenBuckets interface {
+	// Take removes a token from the bucket of client. If the bucket is
+	// empty it returns how long until the next token is added instead.
+	Take(ctx context.Context, client string) (wait time.Duration, err error)
+}
+
+// rateLimiter limits how often each client may write. Clients are told
+// apart by IP address, taken from X-Forwarded-For for requests that come
+// through a trusted proxy.
+type rateLimiter struct {
+	buckets tokenBuckets
+	trusted trustedProxies
+}
+
+// NewRateLimiter returns the rate limiter for the write routes, or nil if
+// writes are not limited. Buckets in Redis are kept on the master of store,
+// so they are shared by all guestbook replicas.
+func (c *Config) NewRateLimiter(store Store) (*rateLimiter, error) {
+	if c.WriteRate <= 0 {
+		return nil, nil
+	}
+	if c.WriteBurst < 1 {
+		return nil, fmt.Errorf("write burst must be at least 1, got %d", c.WriteBurst)
+	}
+	trusted, err := parseNets(c.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	limiter := &rateLimiter{trusted: trusted}
+	switch c.RateLimitBackend {
+	case StoreMemory:
+		limiter.buckets = newMemoryBuckets(c.WriteRate, c.WriteBurst)
+	case StoreRedis:
+		s, ok := redisStoreOf(store)
+		if !ok {
+			return nil, fmt.Errorf("rate limit backend %q needs -store=%s", StoreRedis, StoreRedis)
+		}
+		limiter.buckets = &redisBuckets{pool: s.master, rate: c.WriteRate, burst: c.WriteBurst}
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q, must be %q or %q", c.RateLimitBackend, StoreMemory, StoreRedis)
+	}
+	return limiter, nil
+}
+
+// limit rejects requests with 429 while their client has no tokens left. A
+// failure to reach the buckets lets the request through, since the write
+// it guards would most likely fail as well.
+func (l *rateLimiter) limit(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		wait, err := l.buckets.Take(req.Context(), l.trusted.clientIP(req))
+		if err != nil {
+			requestLogger(req).Warn("rate limit unavailable, letting the request through", "error", err.Error())
+		} else if wait > 0 {
+			rateLimited.Inc()
+			seconds := int(math.Ceil(wait.Seconds()))
+			rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeError(rw, req, &Error{Status: http.StatusTooManyRequests, Code: CodeRateLimited, Message: fmt.Sprintf("too many writes, retry in %d seconds", seconds)})
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// trustedProxies are the proxies whose X-Forwarded-For header is believed.
+type trustedProxies []*net.IPNet
+
+// clientIP returns the address of the client. When the request comes from
+// a trusted proxy, X-Forwarded-For is followed back from the end past every
+// trusted proxy; earlier addresses could have been made up by the client.
+func (p trustedProxies) clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if !p.isTrusted(host) {
+		return host
+	}
+	var forwarded []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(addr) == nil {
+			break
+		}
+		host = addr
+		if !p.isTrusted(addr) {
+			break
+		}
+	}
+	return host
+}
+
+func (p trustedProxies) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	for _, n := range p {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNets parses a comma separated list of CIDRs and plain addresses.
+func parseNets(list string) (trustedProxies, error) {
+	var nets trustedProxies
+	for _, s := range splitList(list) {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// memoryBuckets keeps the buckets in process memory, so each guestbook
+// replica limits clients on its own.
+type memoryBuckets struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newMemoryBuckets(rate float64, burst int) *memoryBuckets {
+	return &memoryBuckets{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket), lastPrune: time.Now()}
+}
+
+func (m *memoryBuckets) Take(ctx context.Context, client string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.prune(now)
+
+	b, ok := m.buckets[client]
+	if !ok {
+		b = &bucket{tokens: m.burst, last: now}
+		m.buckets[client] = b
+	}
+	b.tokens = math.Min(m.burst, b.tokens+now.Sub(b.last).Seconds()*m.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / m.rate * float64(time.Second)), nil
+	}
+	b.tokens--
+	return 0, nil
+}
+
+// prune drops the buckets that have filled up again, which are the same as
+// no bucket at all, at most once a minute.
+func (m *memoryBuckets) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < time.Minute {
+		return
+	}
+	m.lastPrune = now
+	for client, b := range m.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*m.rate >= m.burst {
+			delete(m.buckets, client)
+		}
+	}
+}
+
+// takeScript refills and takes from the bucket in KEYS[1] atomically. The
+// arguments are the rate in tokens per second, the burst and the
+// current time in milliseconds; it returns how many milliseconds to wait,
+// 0 if a token was taken. A bucket expires once it would be full again.
+var takeScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1]) / 1000
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local wait = 0
+if tokens < 1 then
+	wait = math.ceil((1 - tokens) / rate)
+else
+	tokens = tokens - 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+return wait
+`)
+
+// redisBuckets keeps the buckets in Redis. The guestbook replicas supply
+// the time, so their clocks should roughly agree.
+type redisBuckets struct {
+	pool  redisPool
+	rate  float64 // tokens per second
+	burst int
+}
+
+func rateLimitKey(client string) string {
+	return "guestbook:ratelimit:" + client
+}
+
+func (r *redisBuckets) Take(ctx context.Context, client string) (time.Duration, error) {
+	key := rateLimitKey(client)
+	conn := r.pool.Get(ctx, key)
+	defer conn.Close()
+	wait, err := redis.Int64(takeScript.Do(conn, key, strconv.FormatFloat(r.rate, 'f', -1, 64), r.burst, time.Now().UnixMilli()))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
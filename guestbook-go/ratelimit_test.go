@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServerWithConfig(t, s, &Config{
+				LegacyRoutes:     true,
+				WriteRate:        0.001,
+				WriteBurst:       2,
+				RateLimitBackend: name,
+			})
+			entries := server.URL + "/api/v1/lists/guestbook/entries"
+
+			expectStatus(t, do(t, "POST", entries, `{"value": "one"}`), http.StatusCreated)
+			expectStatus(t, do(t, "GET", server.URL+"/rpush/guestbook/two", ""), http.StatusOK)
+			resp := do(t, "POST", entries, `{"value": "three"}`)
+			expectError(t, resp, http.StatusTooManyRequests, CodeRateLimited)
+			if retry := resp.Header.Get("Retry-After"); retry == "" || retry == "0" {
+				t.Errorf("expected a Retry-After header, got %q", retry)
+			}
+
+			// Reads are not limited.
+			expectStatus(t, do(t, "GET", entries, ""), http.StatusOK)
+		})
+	}
+}
+
+func TestRateLimitBackend(t *testing.T) {
+	config := Config{WriteRate: 1, WriteBurst: 1, RateLimitBackend: StoreRedis}
+	if _, err := config.NewRateLimiter(newMemoryStore()); err == nil {
+		t.Errorf("expected an error for Redis rate limits without the Redis store")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseNets("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote    string
+		forwarded []string
+		expected  string
+	}{
+		{"203.0.113.1:1234", nil, "203.0.113.1"},
+		// Only trusted proxies may set X-Forwarded-For.
+		{"203.0.113.1:1234", []string{"198.51.100.1"}, "203.0.113.1"},
+		{"10.1.2.3:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"192.168.1.1:1234", []string{"198.51.100.1, 10.0.0.1"}, "198.51.100.1"},
+		{"10.1.2.3:1234", []string{"6.6.6.6", "198.51.100.1, 10.0.0.1"}, "198.51.100.1"},
+		{"10.1.2.3:1234", []string{"10.0.0.2, 10.0.0.1"}, "10.0.0.2"},
+		{"10.1.2.3:1234", []string{"garbage"}, "10.1.2.3"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = test.remote
+		for _, header := range test.forwarded {
+			req.Header.Add("X-Forwarded-For", header)
+		}
+		if got := trusted.clientIP(req); got != test.expected {
+			t.Errorf("clientIP(%s, %q): expected %s, got %s", test.remote, test.forwarded, test.expected, got)
+		}
+	}
+
+	if _, err := parseNets("10.0.0.0/33"); err == nil {
+		t.Errorf("expected an error for an invalid CIDR")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// sentinelTimeout bounds every exchange with a single Sentinel, so that an
+// unresponsive one does not hold up the others.
+const sentinelTimeout = time.Second
+
+// sentinel finds the servers of a master group through Redis Sentinel. The
+// Sentinels are asked in turn until one of them answers.
+type sentinel struct {
+	addrs  []string
+	master string
+}
+
+func (c *Config) newSentinel() *sentinel {
+	return &sentinel{addrs: splitList(c.SentinelAddrs), master: c.SentinelMaster}
+}
+
+// newSentinelPool returns a pool that asks addr for the server of every new
+// connection. A pooled connection to the master is dropped once the server
+// is no longer the master, which is how writes follow a failover.
+func (c *Config) newSentinelPool(name string, addr func() (string, error)) connPool {
+	testOnBorrow := pingOnBorrow
+	if name == "master" {
+		testOnBorrow = checkMaster
+	}
+	return connPool{c.newPool(name, addr, testOnBorrow), name}
+}
+
+func checkMaster(c redis.Conn, t time.Time) error {
+	role, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(role) == 0 {
+		return errors.New("empty reply to ROLE")
+	}
+	if r, _ := redis.String(role[0], nil); r != "master" {
+		return fmt.Errorf("server is now a %s", r)
+	}
+	return nil
+}
+
+// masterAddr returns the host:port of the current master.
+func (s *sentinel) masterAddr() (string, error) {
+	var addr string
+	err := s.ask(func(conn redis.Conn) error {
+		reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.master))
+		if err == redis.ErrNil {
+			return fmt.Errorf("%w: sentinel does not know master %q", errNoServer, s.master)
+		} else if err != nil {
+			return err
+		}
+		if len(reply) != 2 {
+			return fmt.Errorf("unexpected reply %q to SENTINEL get-master-addr-by-name", reply)
+		}
+		addr = net.JoinHostPort(reply[0], reply[1])
+		return nil
+	})
+	return addr, err
+}
+
+// slaveAddr returns the host:port of a randomly chosen slave that Sentinel
+// considers healthy, so that reads are spread over the slaves.
+func (s *sentinel) slaveAddr() (string, error) {
+	var addrs []string
+	err := s.ask(func(conn redis.Conn) error {
+		slaves, err := redis.Values(conn.Do("SENTINEL", "slaves", s.master))
+		if err != nil {
+			return err
+		}
+		addrs = nil
+		for _, slave := range slaves {
+			fields, err := redis.StringMap(slave, nil)
+			if err != nil {
+				return err
+			}
+			if !healthySlave(fields["flags"]) {
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(fields["ip"], fields["port"]))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("%w: sentinel knows no healthy slave of %q", errNoServer, s.master)
+	}
+	return addrs[rand.Intn(len(addrs))], nil
+}
+
+// healthySlave reports whether the flags Sentinel lists for a slave allow
+// reading from it.
+func healthySlave(flags string) bool {
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "s_down", "o_down", "disconnected":
+			return false
+		}
+	}
+	return true
+}
+
+// ask runs fn against each Sentinel in turn until it succeeds, and returns
+// the last error if it fails on all of them.
+func (s *sentinel) ask(fn func(conn redis.Conn) error) error {
+	err := fmt.Errorf("%w: no sentinels configured", errNoServer)
+	for _, addr := range s.addrs {
+		var conn redis.Conn
+		conn, err = redis.Dial("tcp", addr,
+			redis.DialConnectTimeout(sentinelTimeout),
+			redis.DialReadTimeout(sentinelTimeout),
+			redis.DialWriteTimeout(sentinelTimeout))
+		if err != nil {
+			continue
+		}
+		err = fn(conn)
+		conn.Close()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+)
+
+// sentinelSlave is a slave as listed by the fake Sentinel.
+type sentinelSlave struct {
+	server *miniredis.Miniredis
+	flags  string
+}
+
+// newTestSentinel returns a fake Sentinel that monitors master, as
+// "mymaster", and slaves. The master also gets the ROLE command, which the
+// fakes lack.
+func newTestSentinel(t *testing.T, master *miniredis.Miniredis, slaves ...sentinelSlave) *miniredis.Miniredis {
+	t.Helper()
+	master.Server().Register("ROLE", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(3)
+		c.WriteBulk("master")
+		c.WriteInt(0)
+		c.WriteLen(0)
+	})
+
+	sentinel := miniredis.RunT(t)
+	sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) != 2 || args[1] != "mymaster" {
+			c.WriteNull()
+			return
+		}
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			c.WriteLen(2)
+			c.WriteBulk(master.Host())
+			c.WriteBulk(master.Port())
+		case "slaves":
+			c.WriteLen(len(slaves))
+			for _, slave := range slaves {
+				c.WriteLen(6)
+				for _, field := range []string{"ip", slave.server.Host(), "port", slave.server.Port(), "flags", slave.flags} {
+					c.WriteBulk(field)
+				}
+			}
+		default:
+			c.WriteError("ERR unknown SENTINEL subcommand")
+		}
+	})
+	return sentinel
+}
+
+func newTestSentinelStore(t *testing.T, sentinel *miniredis.Miniredis) *redisStore {
+	t.Helper()
+	// The first Sentinel is unreachable, so the second one must be asked.
+	config := Config{RedisMode: RedisSentinel, SentinelAddrs: "127.0.0.1:1, " + sentinel.Addr(), SentinelMaster: "mymaster"}
+	master, slave, err := config.NewPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &redisStore{master: master, slave: slave}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSentinel(t *testing.T) {
+	master := miniredis.RunT(t)
+	down := miniredis.RunT(t)
+	slave := miniredis.RunT(t)
+	sentinel := newTestSentinel(t, master, sentinelSlave{down, "slave,s_down"}, sentinelSlave{slave, "slave"})
+	s := newTestSentinelStore(t, sentinel)
+
+	for _, value := range []string{"a", "b"} {
+		if _, err := s.Append(context.Background(), "guestbook", Entry{Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if values, _ := master.List("guestbook"); len(values) != 2 {
+		t.Errorf("expected the writes on the master, got %q", values)
+	}
+
+	// Reads must come from the healthy slave only.
+	down.RPush("guestbook", "down")
+	slave.RPush("guestbook", "replicated")
+	for i := 0; i < 5; i++ {
+		entries, _, err := s.Range(context.Background(), "guestbook", Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []Entry{{Index: 0, Value: "replicated"}}; !reflect.DeepEqual(withoutMetadata(entries...), expected) {
+			t.Fatalf("expected the read from the healthy slave %+v, got %+v", expected, entries)
+		}
+	}
+}
+
+func TestSentinelNoSlaves(t *testing.T) {
+	master := miniredis.RunT(t)
+	s := newTestSentinelStore(t, newTestSentinel(t, master))
+
+	if _, _, err := s.Range(context.Background(), "guestbook", Page{}); !isUnavailable(err) {
+		t.Errorf("expected redis to be unavailable without slaves, got %v", err)
+	}
+}
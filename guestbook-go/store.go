@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Names of the storage backends accepted by the -store flag.
+const (
+	StoreRedis  = "redis"
+	StoreMemory = "memory"
+)
+
+// ErrNotFound is returned for an entry or a guestbook that does not exist.
+var ErrNotFound = errors.New("entry not found")
+
+// Store holds the guestbook lists. Errors that should be reported with a
+// particular HTTP status are returned as *Error.
+type Store interface {
+	// Append adds entry to the end of the list and returns it with its
+	// index, and with an ID and creation time unless it had them already.
+	Append(ctx context.Context, key string, entry Entry) (Entry, error)
+	// Range returns the selected page of the list together with the
+	// length of the whole list.
+	Range(ctx context.Context, key string, page Page) ([]Entry, int, error)
+	// Len returns the length of the list.
+	Len(ctx context.Context, key string) (int, error)
+	// Guestbooks returns the names of the lists in sorted order. A list is
+	// a guestbook once it has been created or appended to.
+	Guestbooks(ctx context.Context) ([]string, error)
+	// CreateGuestbook adds an empty guestbook and reports whether there was
+	// no guestbook of that name yet.
+	CreateGuestbook(ctx context.Context, key string) (bool, error)
+	// DeleteGuestbook removes the guestbook together with its entries.
+	DeleteGuestbook(ctx context.Context, key string) error
+	// Remove takes the entry with the given ID out of the list and returns
+	// it, or ErrNotFound.
+	Remove(ctx context.Context, key, id string) (Entry, error)
+	// Hold keeps entry back from the list, such as while it is hidden or
+	// awaits approval, and returns it as Append would.
+	Hold(ctx context.Context, key string, entry Entry) (Entry, error)
+	// Release stops holding back the entry with the given ID and returns
+	// it, or ErrNotFound.
+	Release(ctx context.Context, key, id string) (Entry, error)
+	// Held returns the entries held back from the list, oldest first.
+	Held(ctx context.Context, key string) ([]Entry, error)
+	// Restore atomically replaces the list with entries and the entries
+	// held back from it with held, keeping their IDs and creation times.
+	Restore(ctx context.Context, key string, entries, held []Entry) error
+	// Audit records a moderation action.
+	Audit(ctx context.Context, record AuditRecord) error
+	// AuditLog returns up to limit of the latest moderation actions,
+	// newest first.
+	AuditLog(ctx context.Context, limit int) ([]AuditRecord, error)
+	// Info returns the INFO output of the servers behind the store. Only
+	// failing to reach the primary server is an error; other servers are
+	// reported with their error.
+	Info(ctx context.Context) ([]ServerInfo, error)
+	// Subscribe returns a channel on which entries appended to the list are
+	// delivered until ctx is done. The channel is closed when the
+	// subscription ends, including when the backend fails.
+	Subscribe(ctx context.Context, key string) (<-chan Entry, error)
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// NewStore returns the storage backend selected by the configuration.
+func (c *Config) NewStore() (Store, error) {
+	switch c.Store {
+	case StoreRedis:
+		if err := validateConsistency(c.Consistency); err != nil {
+			return nil, err
+		}
+		master, slave, err := c.NewPools()
+		if err != nil {
+			return nil, err
+		}
+		s := &redisStore{
+			master:       master,
+			slave:        slave,
+			consistency:  c.Consistency,
+			waitReplicas: c.ConsistencyReplicas,
+			waitTimeout:  c.ConsistencyTimeout,
+			maxLength:    c.MaxListLength,
+		}
+		if c.FailoverThreshold > 0 {
+			s.breaker = newSlaveBreaker(c.FailoverThreshold, c.FailoverCooldown)
+		}
+		switch c.ListFormat {
+		case ListFormatList:
+			return s, nil
+		case ListFormatPHP:
+			return &phpStore{s}, nil
+		}
+		s.Close()
+		return nil, fmt.Errorf("unknown list format %q, must be %q or %q", c.ListFormat, ListFormatList, ListFormatPHP)
+	case StoreMemory:
+		if c.ListFormat != ListFormatList {
+			return nil, fmt.Errorf("list format %q needs -store=%s", c.ListFormat, StoreRedis)
+		}
+		s := newMemoryStore()
+		s.maxLength = c.MaxListLength
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown store %q, must be %q or %q", c.Store, StoreRedis, StoreMemory)
+}
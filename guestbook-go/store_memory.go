@@ -0,0 +1,296 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// subscriberBuffer is how many entries a slow subscriber may fall behind
+// before entries are dropped for it. Clients notice the gap in the indexes
+// and reload the list.
+const subscriberBuffer = 16
+
+// memoryStore keeps the lists in process memory. It is meant for running
+// the guestbook locally and in tests; every replica has its own lists.
+type memoryStore struct {
+	// maxLength is how many entries a list keeps, 0 for no limit.
+	maxLength int
+
+	mu sync.Mutex
+	// lists hold the entries in their stored form, as in Redis.
+	lists       map[string][]string
+	guestbooks  map[string]struct{}
+	held        map[string]map[string]Entry
+	audit       []AuditRecord
+	subscribers map[string]map[chan Entry]struct{}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		lists:       make(map[string][]string),
+		guestbooks:  make(map[string]struct{}),
+		held:        make(map[string]map[string]Entry),
+		subscribers: make(map[string]map[chan Entry]struct{}),
+	}
+}
+
+func (s *memoryStore) Append(ctx context.Context, key string, entry Entry) (Entry, error) {
+	entry, record, err := encodeEntry(entry)
+	if err != nil {
+		return Entry{}, encodeFailed(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guestbooks[key] = struct{}{}
+	list := append(s.lists[key], record)
+	if s.maxLength > 0 && len(list) > s.maxLength {
+		// Copy rather than reslice so that the dropped entries are freed.
+		list = append([]string(nil), list[len(list)-s.maxLength:]...)
+	}
+	s.lists[key] = list
+	entry.Index = len(s.lists[key]) - 1
+	for ch := range s.subscribers[key] {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+	return entry, nil
+}
+
+func (s *memoryStore) Range(ctx context.Context, key string, page Page) ([]Entry, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.lists[key]
+	start, stop := page.bounds()
+	return page.entries(lrange(list, start, stop), len(list), decodeEntry), len(list), nil
+}
+
+func (s *memoryStore) Len(ctx context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.lists[key]), nil
+}
+
+func (s *memoryStore) Guestbooks(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.guestbooks))
+	for name := range s.guestbooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memoryStore) CreateGuestbook(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.guestbooks[key]; ok {
+		return false, nil
+	}
+	s.guestbooks[key] = struct{}{}
+	return true, nil
+}
+
+func (s *memoryStore) DeleteGuestbook(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.guestbooks[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.guestbooks, key)
+	delete(s.lists, key)
+	delete(s.held, key)
+	return nil
+}
+
+func (s *memoryStore) Remove(ctx context.Context, key, id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.lists[key]
+	for i, value := range list {
+		if entry := decodeEntry(i, value); entry.ID == id {
+			if len(list) == 1 {
+				delete(s.lists, key)
+			} else {
+				s.lists[key] = append(list[:i], list[i+1:]...)
+			}
+			return entry, nil
+		}
+	}
+	return Entry{}, ErrNotFound
+}
+
+func (s *memoryStore) Hold(ctx context.Context, key string, entry Entry) (Entry, error) {
+	entry, _, err := encodeEntry(entry)
+	if err != nil {
+		return Entry{}, encodeFailed(err)
+	}
+	entry.Index = 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guestbooks[key] = struct{}{}
+	if s.held[key] == nil {
+		s.held[key] = make(map[string]Entry)
+	}
+	s.held[key][entry.ID] = entry
+	return entry, nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, key, id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.held[key][id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	delete(s.held[key], id)
+	if len(s.held[key]) == 0 {
+		delete(s.held, key)
+	}
+	return entry, nil
+}
+
+func (s *memoryStore) Held(ctx context.Context, key string) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, 0, len(s.held[key]))
+	for _, entry := range s.held[key] {
+		entries = append(entries, entry)
+	}
+	sortByCreation(entries)
+	return entries, nil
+}
+
+func (s *memoryStore) Restore(ctx context.Context, key string, entries, held []Entry) error {
+	list := make([]string, len(entries))
+	for i, entry := range entries {
+		value, err := storedForm(entry)
+		if err != nil {
+			return encodeFailed(err)
+		}
+		list[i] = value
+	}
+	if s.maxLength > 0 && len(list) > s.maxLength {
+		list = list[len(list)-s.maxLength:]
+	}
+	heldByID := make(map[string]Entry, len(held))
+	for _, entry := range held {
+		entry, _, err := encodeEntry(entry)
+		if err != nil {
+			return encodeFailed(err)
+		}
+		entry.Index = 0
+		heldByID[entry.ID] = entry
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guestbooks[key] = struct{}{}
+	delete(s.lists, key)
+	if len(list) > 0 {
+		s.lists[key] = list
+	}
+	delete(s.held, key)
+	if len(heldByID) > 0 {
+		s.held[key] = heldByID
+	}
+	return nil
+}
+
+func (s *memoryStore) Audit(ctx context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(s.audit, record)
+	if len(s.audit) > maxAuditRecords {
+		s.audit = append([]AuditRecord(nil), s.audit[len(s.audit)-maxAuditRecords:]...)
+	}
+	return nil
+}
+
+func (s *memoryStore) AuditLog(ctx context.Context, limit int) ([]AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := []AuditRecord{}
+	for i := len(s.audit) - 1; i >= 0 && len(records) < limit; i-- {
+		records = append(records, s.audit[i])
+	}
+	return records, nil
+}
+
+func (s *memoryStore) Info(ctx context.Context) ([]ServerInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := 0
+	for _, list := range s.lists {
+		entries += len(list)
+	}
+	text := fmt.Sprintf("# Store\r\nstore:%s\r\nguestbooks:%d\r\nlists:%d\r\nentries:%d\r\n", StoreMemory, len(s.guestbooks), len(s.lists), entries)
+	return []ServerInfo{{Role: StoreMemory, Text: text}}, nil
+}
+
+func (s *memoryStore) Subscribe(ctx context.Context, key string) (<-chan Entry, error) {
+	ch := make(chan Entry, subscriberBuffer)
+	s.mu.Lock()
+	if s.subscribers[key] == nil {
+		s.subscribers[key] = make(map[chan Entry]struct{})
+	}
+	s.subscribers[key][ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers[key], ch)
+		if len(s.subscribers[key]) == 0 {
+			delete(s.subscribers, key)
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// lrange returns list[start:stop+1] with the index semantics of LRANGE:
+// negative indexes count from the end and out of range indexes are clamped.
+func lrange(list []string, start, stop int) []string {
+	if start < 0 {
+		start += len(list)
+	}
+	if stop < 0 {
+		stop += len(list)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= len(list) {
+		stop = len(list) - 1
+	}
+	if start > stop {
+		return nil
+	}
+	return append([]string(nil), list[start:stop+1]...)
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// List formats of the Redis store.
+const (
+	// ListFormatList keeps every list in a Redis list of entry records.
+	ListFormatList = "list"
+	// ListFormatPHP keeps every list in a string of comma separated values,
+	// as the PHP guestbook in guestbook/php-redis does.
+	ListFormatPHP = "php"
+)
+
+// phpSeparator joins the values of a list in the PHP format, as the
+// JavaScript Array.join of the PHP guestbook does.
+const phpSeparator = ","
+
+// phpUnsafe are the characters a value in the PHP format must not contain.
+// Besides phpSeparator, the PHP guestbook puts the whole list unescaped into
+// the query of a URL, where &, #, + and % change its meaning, and into a JSON
+// string, where " and \ do.
+const phpUnsafe = phpSeparator + `"\&#+%`
+
+// maxUpdateAttempts bounds how often an update of a list in the PHP format
+// is retried when another client changed the list meanwhile.
+const maxUpdateAttempts = 10
+
+// phpStore is a redisStore that keeps the lists in the PHP format, so that
+// the Go and PHP guestbooks can share one Redis. Only the values are stored,
+// so entries have the IDs of bare values and no other metadata, and values
+// must not contain any of phpUnsafe. The held entries, the set of guestbooks and
+// the audit log are kept as by the redisStore.
+type phpStore struct {
+	*redisStore
+}
+
+// splitPHP returns the values of a list in the PHP format. Empty values are
+// dropped, such as the one the PHP guestbook starts a new list with.
+func splitPHP(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, phpSeparator) {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// phpEntry is decodeEntry for a value of a list in the PHP format, which is
+// always bare.
+func phpEntry(index int, value string) Entry {
+	return Entry{Index: index, ID: bareEntryID(value), Value: value}
+}
+
+// checkPHPValue rejects a value the PHP guestbook could not read back.
+func checkPHPValue(value string) error {
+	if strings.ContainsAny(value, phpUnsafe) {
+		return badRequest(CodeBadRequest, "value must not contain any of %s with the %s list format", phpUnsafe, ListFormatPHP)
+	}
+	return nil
+}
+
+// getPHP reads the values of the list in the PHP format.
+func getPHP(conn redis.Conn, key string) ([]string, error) {
+	s, err := redis.String(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return splitPHP(s), nil
+}
+
+// sendPHP queues the command that stores values as the list in the PHP
+// format.
+func sendPHP(conn redis.Conn, key string, values []string) {
+	if len(values) == 0 {
+		conn.Send("DEL", key)
+	} else {
+		conn.Send("SET", key, strings.Join(values, phpSeparator))
+	}
+}
+
+// update replaces the values of the list with what fn returns for them, and
+// returns those. Since the PHP guestbook writes the whole list at once, the
+// list is WATCHed and the update retried if it changed before the EXEC.
+func (s *phpStore) update(conn redis.Conn, key string, fn func(values []string) ([]string, error)) ([]string, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return nil, redisError(err)
+		}
+		values, err := getPHP(conn, key)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return nil, redisError(err)
+		}
+		if values, err = fn(values); err != nil {
+			conn.Do("UNWATCH")
+			return nil, err
+		}
+		conn.Send("MULTI")
+		sendPHP(conn, key, values)
+		if _, err := redis.Values(conn.Do("EXEC")); err == nil {
+			return values, nil
+		} else if err != redis.ErrNil {
+			return nil, redisError(err)
+		}
+	}
+	return nil, &Error{Status: http.StatusConflict, Code: CodeConflict, Message: "list kept changing during the update"}
+}
+
+// Guestbooks returns the lists in the PHP format that are missing from the
+// set, such as the ones the PHP guestbook wrote.
+func (s *phpStore) Guestbooks(ctx context.Context) ([]string, error) {
+	return s.guestbooks(ctx, redisType(ListFormatPHP))
+}
+
+func (s *phpStore) Append(ctx context.Context, key string, entry Entry) (Entry, error) {
+	if err := checkPHPValue(entry.Value); err != nil {
+		return Entry{}, err
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return Entry{}, err
+	}
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	values, err := s.update(conn, key, func(values []string) ([]string, error) {
+		values = append(values, entry.Value)
+		if s.maxLength > 0 && len(values) > s.maxLength {
+			values = values[len(values)-s.maxLength:]
+		}
+		return values, nil
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	entry = phpEntry(len(values)-1, entry.Value)
+	s.appended(conn, key, entry)
+	return entry, nil
+}
+
+func (s *phpStore) Range(ctx context.Context, key string, page Page) (entries []Entry, total int, err error) {
+	err = s.read(func(pool redisPool) error {
+		entries, total, err = rangePHP(ctx, pool, key, page)
+		return err
+	})
+	return entries, total, err
+}
+
+func (s *phpStore) RangeAfterWrite(ctx context.Context, key string, page Page) ([]Entry, int, error) {
+	if s.consistency == ConsistencyMaster {
+		return rangePHP(ctx, s.master, key, page)
+	}
+	return s.Range(ctx, key, page)
+}
+
+func rangePHP(ctx context.Context, pool redisPool, key string, page Page) ([]Entry, int, error) {
+	conn := pool.Get(ctx, key)
+	defer conn.Close()
+	values, err := getPHP(conn, key)
+	if err != nil {
+		return nil, 0, redisError(err)
+	}
+	start, stop := page.bounds()
+	return page.entries(lrange(values, start, stop), len(values), phpEntry), len(values), nil
+}
+
+func (s *phpStore) Len(ctx context.Context, key string) (length int, err error) {
+	err = s.read(func(pool redisPool) error {
+		conn := pool.Get(ctx, key)
+		defer conn.Close()
+		values, err := getPHP(conn, key)
+		if err != nil {
+			return redisError(err)
+		}
+		length = len(values)
+		return nil
+	})
+	return length, err
+}
+
+func (s *phpStore) Remove(ctx context.Context, key, id string) (Entry, error) {
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	var removed Entry
+	_, err := s.update(conn, key, func(values []string) ([]string, error) {
+		for i, value := range values {
+			if bareEntryID(value) == id {
+				removed = phpEntry(i, value)
+				return append(values[:i:i], values[i+1:]...), nil
+			}
+		}
+		return nil, ErrNotFound
+	})
+	return removed, err
+}
+
+// Restore keeps only the values of entries, which must not contain any of
+// phpUnsafe.
+func (s *phpStore) Restore(ctx context.Context, key string, entries, held []Entry) error {
+	values := make([]string, len(entries))
+	for i, entry := range entries {
+		if err := checkPHPValue(entry.Value); err != nil {
+			return err
+		}
+		values[i] = entry.Value
+	}
+	if s.maxLength > 0 && len(values) > s.maxLength {
+		values = values[len(values)-s.maxLength:]
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return err
+	}
+
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	conn.Send("MULTI")
+	sendPHP(conn, key, values)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return redisError(err)
+	}
+	return s.restoreHeld(ctx, key, held)
+}
+
+// redisStoreOf returns the redisStore behind s, whatever its list format.
+func redisStoreOf(s Store) (*redisStore, bool) {
+	switch s := s.(type) {
+	case *redisStore:
+		return s, true
+	case *phpStore:
+		return s.redisStore, true
+	}
+	return nil, false
+}
+
+// redisType is what the Redis TYPE command reports for a list in format.
+func redisType(format string) string {
+	if format == ListFormatPHP {
+		return "string"
+	}
+	return "list"
+}
+
+// migrate converts the list under key on the master to the format to and
+// returns how many entries it holds, and whether it had to be converted.
+// The list is WATCHed, so the conversion fails rather than lose an entry
+// written meanwhile. With dryRun it is only checked that the list can be
+// converted.
+func (s *redisStore) migrate(ctx context.Context, key, to string, dryRun bool) (int, bool, error) {
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	if _, err := conn.Do("WATCH", key); err != nil {
+		return 0, false, redisError(err)
+	}
+	defer conn.Do("UNWATCH")
+
+	kind, err := redis.String(conn.Do("TYPE", key))
+	if err != nil {
+		return 0, false, redisError(err)
+	}
+	var entries []Entry
+	switch kind {
+	case "none":
+		return 0, false, nil
+	case "list":
+		values, err := redis.Strings(conn.Do("LRANGE", key, 0, -1))
+		if err != nil {
+			return 0, false, redisError(err)
+		}
+		for i, value := range values {
+			entries = append(entries, decodeEntry(i, value))
+		}
+	case "string":
+		values, err := getPHP(conn, key)
+		if err != nil {
+			return 0, false, redisError(err)
+		}
+		for i, value := range values {
+			entries = append(entries, phpEntry(i, value))
+		}
+	default:
+		return 0, false, fmt.Errorf("%q holds a %s rather than a list", key, kind)
+	}
+	if kind == redisType(to) {
+		return len(entries), false, nil
+	}
+
+	values := make([]string, len(entries))
+	for i, entry := range entries {
+		if to == ListFormatPHP {
+			if err := checkPHPValue(entry.Value); err != nil {
+				return 0, false, fmt.Errorf("entry %d: %w", i, err)
+			}
+			values[i] = entry.Value
+		} else if values[i], err = storedForm(entry); err != nil {
+			return 0, false, encodeFailed(err)
+		}
+	}
+	if dryRun {
+		return len(entries), true, nil
+	}
+
+	conn.Send("MULTI")
+	conn.Send("DEL", key)
+	if to == ListFormatPHP {
+		sendPHP(conn, key, values)
+	} else {
+		for start := 0; start < len(values); start += restoreBatch {
+			args := []interface{}{key}
+			for _, value := range values[start:min(start+restoreBatch, len(values))] {
+				args = append(args, value)
+			}
+			conn.Send("RPUSH", args...)
+		}
+	}
+	if _, err := redis.Values(conn.Do("EXEC")); err == redis.ErrNil {
+		return 0, false, fmt.Errorf("%q changed during the migration, try again", key)
+	} else if err != nil {
+		return 0, false, redisError(err)
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return 0, false, err
+	}
+	return len(entries), true, nil
+}
+
+// runMigrate runs the migrate subcommand in args, which converts lists on
+// the Redis master of config between the list formats. Errors are fatal.
+func runMigrate(config *Config, args []string) {
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	to := flags.String("to", ListFormatList, "List format to convert to, list or php.")
+	dryRun := flags.Bool("dry-run", false, "Only check that the lists can be converted.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] migrate [-to list|php] [-dry-run] [list...]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args[1:])
+	if *to != ListFormatList && *to != ListFormatPHP {
+		fatal("invalid command line", fmt.Errorf("unknown list format %q, must be %q or %q", *to, ListFormatList, ListFormatPHP))
+	}
+
+	store, err := config.NewStore()
+	if err != nil {
+		fatal("creating the store failed", err)
+	}
+	defer store.Close()
+	s, ok := redisStoreOf(store)
+	if !ok {
+		fatal("invalid configuration", fmt.Errorf("migrating needs -store=%s", StoreRedis))
+	}
+	ctx := context.Background()
+
+	keys := flags.Args()
+	if len(keys) == 0 {
+		if keys, err = s.guestbooks(ctx, redisType(ListFormatList), redisType(ListFormatPHP)); err != nil {
+			fatal("listing the guestbooks failed", err)
+		}
+	}
+	for _, key := range keys {
+		if err := validateKey(key); err != nil {
+			fatal("invalid command line", err)
+		}
+		entries, converted, err := s.migrate(ctx, key, *to, *dryRun)
+		if err != nil {
+			fatal("migrating failed", fmt.Errorf("%s: %w", key, err))
+		}
+		slog.Info("list migrated", "key", key, "format", *to, "entries", entries, "converted", converted, "dry_run", *dryRun)
+	}
+}
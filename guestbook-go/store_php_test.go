@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPHPStore(t *testing.T) {
+	s, master := newTestSharedRedisStore(t)
+	// As written by the PHP guestbook, which starts from an empty value.
+	master.Set("messages", ",hello,world")
+	server := newTestServerWithConfig(t, &phpStore{s}, &Config{AdminToken: "secret"})
+	entries := server.URL + "/api/v1/lists/messages/entries"
+
+	var list []Entry
+	decode(t, do(t, "GET", entries, ""), &list)
+	if expected := []Entry{{Index: 0, ID: bareEntryID("hello"), Value: "hello"}, {Index: 1, ID: bareEntryID("world"), Value: "world"}}; !reflect.DeepEqual(list, expected) {
+		t.Errorf("expected %+v, got %+v", expected, list)
+	}
+
+	resp := do(t, "POST", entries, `{"value": "from go", "author": "Ann"}`)
+	expectStatus(t, resp, http.StatusCreated)
+	var entry Entry
+	decode(t, resp, &entry)
+	if expected := (Entry{Index: 2, ID: bareEntryID("from go"), Value: "from go"}); entry != expected {
+		t.Errorf("expected %+v, got %+v", expected, entry)
+	}
+	for _, c := range phpUnsafe {
+		expectError(t, do(t, "POST", entries, `{"value": `+strconv.Quote("a"+string(c)+"b")+`}`), http.StatusBadRequest, CodeBadRequest)
+	}
+	auth := []string{"Authorization", "Bearer secret"}
+	admin := server.URL + "/api/v1/admin/lists/messages/entries/"
+	expectStatus(t, do(t, "DELETE", admin+bareEntryID("hello"), "", auth...), http.StatusNoContent)
+	expectError(t, do(t, "DELETE", admin+"missing", "", auth...), http.StatusNotFound, CodeNotFound)
+	if value, _ := master.Get("messages"); value != "world,from go" {
+		t.Errorf("expected the PHP format, got %q", value)
+	}
+
+	list = nil
+	decode(t, do(t, "GET", entries+"?order=newest-first&limit=1", ""), &list)
+	if expected := []Entry{{Index: 1, Value: "from go"}}; !reflect.DeepEqual(withoutMetadata(list...), expected) {
+		t.Errorf("expected %+v, got %+v", expected, list)
+	}
+
+	for _, c := range phpUnsafe {
+		if err := (&phpStore{s}).Restore(context.Background(), "messages", []Entry{{Value: "a" + string(c) + "b"}}, nil); err == nil {
+			t.Errorf("expected a value with %q not to be restored", c)
+		}
+	}
+	world := admin + bareEntryID("world")
+	expectStatus(t, do(t, "POST", world+"/hide", "", auth...), http.StatusOK)
+	expectStatus(t, do(t, "POST", world+"/restore", "", auth...), http.StatusOK)
+	if value, _ := master.Get("messages"); value != "from go,world" {
+		t.Errorf("expected the restored entry at the end, got %q", value)
+	}
+
+	expectStatus(t, do(t, "DELETE", server.URL+"/api/v1/guestbooks/messages", "", auth...), http.StatusNoContent)
+	if master.Exists("messages") {
+		t.Error("expected the guestbook to be deleted")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	s, master := newTestSharedRedisStore(t)
+	ctx := context.Background()
+	master.Set("messages", ",hi,there")
+
+	if n, converted, err := s.migrate(ctx, "messages", ListFormatList, true); err != nil || n != 2 || !converted {
+		t.Fatalf("expected a dry run to find 2 entries to convert, got %d, %v, %v", n, converted, err)
+	}
+	if value, _ := master.Get("messages"); value != ",hi,there" {
+		t.Errorf("expected a dry run to change nothing, got %q", value)
+	}
+	if _, _, err := s.migrate(ctx, "messages", ListFormatList, false); err != nil {
+		t.Fatal(err)
+	}
+	if values, _ := master.List("messages"); !reflect.DeepEqual(values, []string{"hi", "there"}) {
+		t.Errorf("expected a list of the values, got %q", values)
+	}
+	if names, _ := s.Guestbooks(ctx); !reflect.DeepEqual(names, []string{"messages"}) {
+		t.Errorf("expected the migrated list to be a guestbook, got %q", names)
+	}
+	if _, converted, err := s.migrate(ctx, "messages", ListFormatList, false); err != nil || converted {
+		t.Errorf("expected a list in the format already to be left alone, got %v, %v", converted, err)
+	}
+
+	if _, err := s.Append(ctx, "messages", Entry{Value: "a record", Author: "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.migrate(ctx, "messages", ListFormatPHP, false); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := master.Get("messages"); value != "hi,there,a record" {
+		t.Errorf("expected the values in the PHP format, got %q", value)
+	}
+
+	for _, c := range phpUnsafe {
+		value := "a" + string(c) + "b"
+		master.Del("unsafe")
+		master.RPush("unsafe", "fine", value)
+		if _, _, err := s.migrate(ctx, "unsafe", ListFormatPHP, false); err == nil {
+			t.Errorf("expected a value with %q not to be converted", c)
+		}
+		if values, _ := master.List("unsafe"); !reflect.DeepEqual(values, []string{"fine", value}) {
+			t.Errorf("expected the list to be kept, got %q", values)
+		}
+	}
+	if n, converted, err := s.migrate(ctx, "missing", ListFormatPHP, false); err != nil || n != 0 || converted {
+		t.Errorf("expected nothing to migrate, got %d, %v, %v", n, converted, err)
+	}
+
+	config := Config{Store: StoreMemory, ListFormat: ListFormatPHP}
+	if _, err := config.NewStore(); err == nil {
+		t.Error("expected the PHP format to need the Redis store")
+	}
+}
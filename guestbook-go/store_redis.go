@@ -0,0 +1,629 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisStore keeps every list in a Redis list. Writes go to the master and
+// reads to the slaves, see the Consistency constants for how the two are
+// kept apart. With a breaker, reads that cannot reach the slaves go to the
+// master instead.
+type redisStore struct {
+	master  redisPool
+	slave   redisPool
+	breaker *slaveBreaker
+	// maxLength is how many entries a list keeps, 0 for no limit.
+	maxLength int
+
+	consistency  string
+	waitReplicas int
+	waitTimeout  time.Duration
+
+	mu sync.Mutex
+	// subscriptions are the Redis subscriptions of the process, by key.
+	subscriptions map[string]*subscription
+}
+
+// guestbooksKey is the Redis set of guestbook names.
+const guestbooksKey = reservedKeyPrefix + "guestbooks"
+
+// auditKey is the Redis list of moderation actions, newest first.
+const auditKey = reservedKeyPrefix + "audit"
+
+// heldKey is the Redis hash of the entries held back from a list, by ID.
+func heldKey(key string) string {
+	return reservedKeyPrefix + "held:" + key
+}
+
+// entriesChannel is the pub/sub channel new entries of a list are published
+// on. PUBLISH is replicated, so subscribers can use the slaves.
+func entriesChannel(key string) string {
+	return "guestbook:entries:" + key
+}
+
+// Append pushes the entry onto the list, trimming the oldest entries beyond
+// the maximum length in the same transaction, and announces the new entry
+// to any subscribers. A failed PUBLISH is only logged since the entry has
+// been stored by then.
+func (s *redisStore) Append(ctx context.Context, key string, entry Entry) (Entry, error) {
+	entry, record, err := encodeEntry(entry)
+	if err != nil {
+		return Entry{}, encodeFailed(err)
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return Entry{}, err
+	}
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	length, err := s.push(conn, key, record)
+	if err != nil {
+		return Entry{}, redisError(err)
+	}
+	entry.Index = length - 1
+	s.appended(conn, key, entry)
+	return entry, nil
+}
+
+// appended waits for the replicas with ConsistencyWait and then publishes
+// the entry that was just appended over conn.
+func (s *redisStore) appended(conn redis.Conn, key string, entry Entry) {
+	if s.consistency == ConsistencyWait {
+		waitForReplicas(conn, s.waitReplicas, s.waitTimeout)
+	}
+	if message, err := json.Marshal(entry); err != nil {
+		slog.Error("encoding entry failed", "key", key, "error", err.Error())
+	} else if _, err := conn.Do("PUBLISH", entriesChannel(key), message); err != nil {
+		slog.Warn("publishing entry failed", "key", key, "error", err.Error())
+	}
+}
+
+// read runs fn against the slave pool, unless the breaker has sent reads to
+// the master, and retries it on the master if the slaves are unavailable.
+func (s *redisStore) read(fn func(pool redisPool) error) error {
+	if s.breaker == nil {
+		return fn(s.slave)
+	}
+	if !s.breaker.allow() {
+		return fn(s.master)
+	}
+	err := fn(s.slave)
+	if !isUnavailable(err) {
+		s.breaker.success()
+		return err
+	}
+	s.breaker.failure(err)
+	return fn(s.master)
+}
+
+// push appends value and returns the length of the list afterwards.
+func (s *redisStore) push(conn redis.Conn, key, value string) (int, error) {
+	if s.maxLength <= 0 {
+		return redis.Int(conn.Do("RPUSH", key, value))
+	}
+	conn.Send("MULTI")
+	conn.Send("RPUSH", key, value)
+	conn.Send("LTRIM", key, -s.maxLength, -1)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	length, err := redis.Int(replies[0], nil)
+	if err != nil {
+		return 0, err
+	}
+	if length > s.maxLength {
+		length = s.maxLength
+	}
+	return length, nil
+}
+
+func (s *redisStore) Range(ctx context.Context, key string, page Page) (entries []Entry, total int, err error) {
+	err = s.read(func(pool redisPool) error {
+		entries, total, err = rangeList(ctx, pool, key, page)
+		return err
+	})
+	return entries, total, err
+}
+
+// RangeAfterWrite reads from the master with ConsistencyMaster. Otherwise the
+// slaves will either have caught up or the client accepts that they may not.
+func (s *redisStore) RangeAfterWrite(ctx context.Context, key string, page Page) ([]Entry, int, error) {
+	if s.consistency == ConsistencyMaster {
+		return rangeList(ctx, s.master, key, page)
+	}
+	return s.Range(ctx, key, page)
+}
+
+// rangeList reads the page and the length of the list in one transaction so
+// that the indexes of the returned entries are consistent with the length.
+func rangeList(ctx context.Context, pool redisPool, key string, page Page) ([]Entry, int, error) {
+	conn := pool.Get(ctx, key)
+	defer conn.Close()
+
+	start, stop := page.bounds()
+	conn.Send("MULTI")
+	conn.Send("LLEN", key)
+	conn.Send("LRANGE", key, start, stop)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, 0, redisError(err)
+	}
+	total, err := redis.Int(replies[0], nil)
+	if err != nil {
+		return nil, 0, redisError(err)
+	}
+	values, err := redis.Strings(replies[1], nil)
+	if err != nil {
+		return nil, 0, redisError(err)
+	}
+	return page.entries(values, total, decodeEntry), total, nil
+}
+
+func (s *redisStore) Len(ctx context.Context, key string) (length int, err error) {
+	err = s.read(func(pool redisPool) error {
+		conn := pool.Get(ctx, key)
+		defer conn.Close()
+		length, err = redis.Int(conn.Do("LLEN", key))
+		if err != nil {
+			return redisError(err)
+		}
+		return nil
+	})
+	return length, err
+}
+
+// Guestbooks also returns the lists that were written before guestbooks were
+// tracked and so are missing from the set.
+func (s *redisStore) Guestbooks(ctx context.Context) ([]string, error) {
+	return s.guestbooks(ctx, redisType(ListFormatList))
+}
+
+// guestbooks returns the guestbooks in the set together with the keys of
+// the given Redis types that are valid list names.
+func (s *redisStore) guestbooks(ctx context.Context, kinds ...string) (names []string, err error) {
+	err = s.read(func(pool redisPool) error {
+		conn := pool.Get(ctx, guestbooksKey)
+		defer conn.Close()
+		names, err = redis.Strings(conn.Do("SMEMBERS", guestbooksKey))
+		if err != nil {
+			return redisError(err)
+		}
+		for _, kind := range kinds {
+			keys, err := scanKeys(ctx, pool, kind)
+			if err != nil {
+				return redisError(err)
+			}
+			names = append(names, keys...)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return slices.Compact(names), err
+}
+
+// scanCount is how many keys SCAN is asked to look at per call.
+const scanCount = 1000
+
+// scanKeys returns the keys of the Redis type kind that are valid list names.
+func scanKeys(ctx context.Context, pool redisPool, kind string) ([]string, error) {
+	var keys []string
+	err := pool.EachNode(ctx, func(conn redis.Conn) error {
+		cursor := "0"
+		for {
+			reply, err := redis.Values(conn.Do("SCAN", cursor, "COUNT", scanCount, "TYPE", kind))
+			if err != nil {
+				return err
+			}
+			var batch []string
+			if _, err := redis.Scan(reply, &cursor, &batch); err != nil {
+				return err
+			}
+			for _, key := range batch {
+				if validateKey(key) == nil {
+					keys = append(keys, key)
+				}
+			}
+			if cursor == "0" {
+				return nil
+			}
+		}
+	})
+	return keys, err
+}
+
+// CreateGuestbook adds key to the set of guestbooks. The set has a
+// connection of its own since in a Redis Cluster it need not be on the node
+// of the list.
+func (s *redisStore) CreateGuestbook(ctx context.Context, key string) (bool, error) {
+	conn := s.master.Get(ctx, guestbooksKey)
+	defer conn.Close()
+	added, err := redis.Int(conn.Do("SADD", guestbooksKey, key))
+	if err != nil {
+		return false, redisError(err)
+	}
+	return added == 1, nil
+}
+
+// DeleteGuestbook also deletes lists that were written before guestbooks
+// were tracked and so are missing from the set.
+func (s *redisStore) DeleteGuestbook(ctx context.Context, key string) error {
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	deleted, err := redis.Int(conn.Do("DEL", key))
+	if err != nil {
+		return redisError(err)
+	}
+
+	heldConn := s.master.Get(ctx, heldKey(key))
+	defer heldConn.Close()
+	if _, err := heldConn.Do("DEL", heldKey(key)); err != nil {
+		return redisError(err)
+	}
+
+	setConn := s.master.Get(ctx, guestbooksKey)
+	defer setConn.Close()
+	removed, err := redis.Int(setConn.Do("SREM", guestbooksKey, key))
+	if err != nil {
+		return redisError(err)
+	}
+	if deleted == 0 && removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Remove finds the entry on the master and removes it by its stored value,
+// so that entries appended or removed meanwhile do not matter.
+func (s *redisStore) Remove(ctx context.Context, key, id string) (Entry, error) {
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	values, err := redis.Strings(conn.Do("LRANGE", key, 0, -1))
+	if err != nil {
+		return Entry{}, redisError(err)
+	}
+	for i, value := range values {
+		entry := decodeEntry(i, value)
+		if entry.ID != id {
+			continue
+		}
+		removed, err := redis.Int(conn.Do("LREM", key, 1, value))
+		if err != nil {
+			return Entry{}, redisError(err)
+		}
+		if removed == 0 {
+			break
+		}
+		return entry, nil
+	}
+	return Entry{}, ErrNotFound
+}
+
+func (s *redisStore) Hold(ctx context.Context, key string, entry Entry) (Entry, error) {
+	entry, record, err := encodeEntry(entry)
+	if err != nil {
+		return Entry{}, encodeFailed(err)
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return Entry{}, err
+	}
+	conn := s.master.Get(ctx, heldKey(key))
+	defer conn.Close()
+	if _, err := conn.Do("HSET", heldKey(key), entry.ID, record); err != nil {
+		return Entry{}, redisError(err)
+	}
+	return entry, nil
+}
+
+func (s *redisStore) Release(ctx context.Context, key, id string) (Entry, error) {
+	conn := s.master.Get(ctx, heldKey(key))
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("HGET", heldKey(key), id)
+	conn.Send("HDEL", heldKey(key), id)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return Entry{}, redisError(err)
+	}
+	record, err := redis.String(replies[0], nil)
+	if err == redis.ErrNil {
+		return Entry{}, ErrNotFound
+	} else if err != nil {
+		return Entry{}, redisError(err)
+	}
+	return decodeEntry(0, record), nil
+}
+
+// Held reads from the master since moderators act on what they see.
+func (s *redisStore) Held(ctx context.Context, key string) ([]Entry, error) {
+	conn := s.master.Get(ctx, heldKey(key))
+	defer conn.Close()
+	records, err := redis.Strings(conn.Do("HVALS", heldKey(key)))
+	if err != nil {
+		return nil, redisError(err)
+	}
+	entries := make([]Entry, len(records))
+	for i, record := range records {
+		entries[i] = decodeEntry(0, record)
+	}
+	sortByCreation(entries)
+	return entries, nil
+}
+
+// restoreBatch is how many values Restore pushes with a single command.
+const restoreBatch = 1000
+
+// Restore replaces the list in a single MULTI/EXEC transaction on the
+// master, trimmed to the maximum length, and then the held entries in
+// another one, since in a Redis Cluster they need not share a node.
+func (s *redisStore) Restore(ctx context.Context, key string, entries, held []Entry) error {
+	values := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		value, err := storedForm(entry)
+		if err != nil {
+			return encodeFailed(err)
+		}
+		values[i] = value
+	}
+	if _, err := s.CreateGuestbook(ctx, key); err != nil {
+		return err
+	}
+
+	conn := s.master.Get(ctx, key)
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", key)
+	for len(values) > 0 {
+		n := min(len(values), restoreBatch)
+		conn.Send("RPUSH", append([]interface{}{key}, values[:n]...)...)
+		values = values[n:]
+	}
+	if s.maxLength > 0 {
+		conn.Send("LTRIM", key, -s.maxLength, -1)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return redisError(err)
+	}
+	return s.restoreHeld(ctx, key, held)
+}
+
+// restoreHeld replaces the entries held back from the list with held.
+func (s *redisStore) restoreHeld(ctx context.Context, key string, held []Entry) error {
+	records := make([]interface{}, 0, 2*len(held))
+	for _, entry := range held {
+		entry, record, err := encodeEntry(entry)
+		if err != nil {
+			return encodeFailed(err)
+		}
+		records = append(records, entry.ID, record)
+	}
+	conn := s.master.Get(ctx, heldKey(key))
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", heldKey(key))
+	for len(records) > 0 {
+		n := min(len(records), 2*restoreBatch)
+		conn.Send("HSET", append([]interface{}{heldKey(key)}, records[:n]...)...)
+		records = records[n:]
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return redisError(err)
+	}
+	return nil
+}
+
+// Audit keeps the latest maxAuditRecords actions.
+func (s *redisStore) Audit(ctx context.Context, record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return encodeFailed(err)
+	}
+	conn := s.master.Get(ctx, auditKey)
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("LPUSH", auditKey, data)
+	conn.Send("LTRIM", auditKey, 0, maxAuditRecords-1)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return redisError(err)
+	}
+	return nil
+}
+
+func (s *redisStore) AuditLog(ctx context.Context, limit int) ([]AuditRecord, error) {
+	conn := s.master.Get(ctx, auditKey)
+	defer conn.Close()
+	values, err := redis.ByteSlices(conn.Do("LRANGE", auditKey, 0, limit-1))
+	if err != nil {
+		return nil, redisError(err)
+	}
+	records := make([]AuditRecord, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal(value, &records[i]); err != nil {
+			return nil, &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "invalid audit record", Err: err}
+		}
+	}
+	return records, nil
+}
+
+// Info returns the full INFO of the master and the replication section of
+// a slave, which together show how far the slaves are behind.
+func (s *redisStore) Info(ctx context.Context) ([]ServerInfo, error) {
+	master, err := serverInfo(ctx, s.master)
+	if err != nil {
+		return nil, redisError(err)
+	}
+	infos := []ServerInfo{{Role: "master", Text: master}}
+	slave, err := serverInfo(ctx, s.slave, "replication")
+	return append(infos, ServerInfo{Role: "slave", Text: slave, Err: err}), nil
+}
+
+func serverInfo(ctx context.Context, pool redisPool, section ...interface{}) (string, error) {
+	conn := pool.Get(ctx, "")
+	defer conn.Close()
+	return redis.String(conn.Do("INFO", section...))
+}
+
+// subscription is the single Redis subscription to the entries of a list,
+// whose messages are passed on to every subscriber in the process.
+type subscription struct {
+	conn        redis.Conn
+	subscribers map[chan Entry]struct{}
+}
+
+// Subscribe shares one subscription per list among the subscribers of the
+// process, so that streams do not each hold a Redis connection. The
+// subscription uses a dedicated connection to a slave, or to the master if
+// the slaves are unavailable, since a subscribed connection cannot be
+// returned to the pool. It is closed once its last subscriber is gone.
+func (s *redisStore) Subscribe(ctx context.Context, key string) (<-chan Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subscriptions[key]
+	if sub == nil {
+		var conn redis.Conn
+		err := s.read(func(pool redisPool) (err error) {
+			conn, err = subscribe(pool, key)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if s.subscriptions == nil {
+			s.subscriptions = make(map[string]*subscription)
+		}
+		sub = &subscription{conn: conn, subscribers: make(map[chan Entry]struct{})}
+		s.subscriptions[key] = sub
+		go s.receive(key, sub)
+	}
+
+	ch := make(chan Entry, subscriberBuffer)
+	sub.subscribers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		s.unsubscribe(key, sub, ch)
+	}()
+	return ch, nil
+}
+
+// subscribe returns a connection subscribed to the entries of key.
+func subscribe(pool redisPool, key string) (redis.Conn, error) {
+	conn, err := pool.Dial()
+	if err != nil {
+		return nil, redisError(err)
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(entriesChannel(key)); err != nil {
+		conn.Close()
+		return nil, redisError(err)
+	}
+	// Wait for the confirmation so that nothing published after Subscribe
+	// returns can be missed.
+	switch v := psc.Receive().(type) {
+	case error:
+		conn.Close()
+		return nil, redisError(v)
+	case redis.Subscription:
+	default:
+		conn.Close()
+		return nil, redisError(fmt.Errorf("unexpected reply %v to SUBSCRIBE", v))
+	}
+	return conn, nil
+}
+
+// receive passes the messages of sub on to its subscribers, dropping them
+// for those that fell behind as memoryStore.Append does. Once the
+// connection fails, or is closed by unsubscribe, the subscribers' channels
+// are closed so that their clients reconnect.
+func (s *redisStore) receive(key string, sub *subscription) {
+	psc := redis.PubSubConn{Conn: sub.conn}
+	for {
+		// Messages may be far apart, so wait for them without the read
+		// timeout of the connection.
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			var entry Entry
+			if err := json.Unmarshal(v.Data, &entry); err != nil {
+				slog.Warn("dropping malformed message", "channel", v.Channel, "error", err.Error())
+				continue
+			}
+			s.mu.Lock()
+			for ch := range sub.subscribers {
+				select {
+				case ch <- entry:
+				default:
+				}
+			}
+			s.mu.Unlock()
+		case error:
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.subscriptions[key] == sub {
+				delete(s.subscriptions, key)
+			}
+			for ch := range sub.subscribers {
+				delete(sub.subscribers, ch)
+				close(ch)
+			}
+			sub.conn.Close()
+			return
+		}
+	}
+}
+
+// unsubscribe removes ch from the subscribers of sub unless receive has
+// already closed it, and closes the subscription once it has none left.
+func (s *redisStore) unsubscribe(key string, sub *subscription, ch chan Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := sub.subscribers[ch]; !ok {
+		return
+	}
+	delete(sub.subscribers, ch)
+	close(ch)
+	if len(sub.subscribers) == 0 {
+		if s.subscriptions[key] == sub {
+			delete(s.subscriptions, key)
+		}
+		sub.conn.Close()
+	}
+}
+
+func (s *redisStore) Close() error {
+	s.master.Close()
+	s.slave.Close()
+	return nil
+}
+
+// HealthChecks PINGs both pools. With a breaker the slaves are optional,
+// since reads fall back to the master while they are down.
+func (s *redisStore) HealthChecks() map[string]HealthCheck {
+	return map[string]HealthCheck{
+		"master": {Check: s.master.Ping},
+		"slave":  {Check: s.slave.Ping, Optional: s.breaker != nil},
+	}
+}
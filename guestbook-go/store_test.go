@@ -0,0 +1,179 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestStoreMaxLength(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			switch s := s.(type) {
+			case *memoryStore:
+				s.maxLength = 2
+			case *redisStore:
+				s.maxLength = 2
+			}
+			var last Entry
+			for _, value := range []string{"a", "b", "c"} {
+				var err error
+				if last, err = s.Append(context.Background(), "guestbook", Entry{Value: value}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if expected := []Entry{{Index: 1, Value: "c"}}; !reflect.DeepEqual(withoutMetadata(last), expected) {
+				t.Errorf("expected the last entry %+v, got %+v", expected, last)
+			}
+			entries, total, err := s.Range(context.Background(), "guestbook", Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := []Entry{{Index: 0, Value: "b"}, {Index: 1, Value: "c"}}; total != 2 || !reflect.DeepEqual(withoutMetadata(entries...), expected) {
+				t.Errorf("expected %+v, got %+v with total %d", expected, entries, total)
+			}
+		})
+	}
+}
+
+func TestStoreSubscribeEnds(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			entries, err := s.Subscribe(ctx, "guestbook")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			select {
+			case _, ok := <-entries:
+				if ok {
+					t.Errorf("expected no entries after cancel")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("subscription was not closed after cancel")
+			}
+		})
+	}
+}
+
+func TestRedisSubscriptionShared(t *testing.T) {
+	s, server := newTestSharedRedisStore(t)
+	channel := entriesChannel("guestbook")
+	ctx, cancel := context.WithCancel(context.Background())
+	first, err := s.Subscribe(ctx, "guestbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Subscribe(context.Background(), "guestbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := server.PubSubNumSub(channel)[channel]; n != 1 {
+		t.Errorf("expected the subscribers to share one subscription, got %d", n)
+	}
+
+	if _, err := s.Append(context.Background(), "guestbook", Entry{Value: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, entries := range []<-chan Entry{first, second} {
+		select {
+		case entry := <-entries:
+			if entry.Value != "hello" {
+				t.Errorf("expected the appended entry, got %+v", entry)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected every subscriber to receive the entry")
+		}
+	}
+
+	cancel()
+	if _, ok := <-first; ok {
+		t.Error("expected no entries after cancel")
+	}
+	if n := server.PubSubNumSub(channel)[channel]; n != 1 {
+		t.Errorf("expected the subscription to stay for the other subscriber, got %d", n)
+	}
+	// Subscribers are told when the connection fails, so that their
+	// clients reconnect.
+	server.Close()
+	select {
+	case _, ok := <-second:
+		if ok {
+			t.Errorf("expected no entries once redis is gone")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the subscription to end with the connection")
+	}
+}
+
+func TestRedisTimeout(t *testing.T) {
+	server := miniredis.RunT(t)
+	config := Config{RedisTimeout: 50 * time.Millisecond}
+	s := &redisStore{master: config.NewPool("master", server.Addr()), slave: config.NewPool("slave", server.Addr())}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, err := s.Subscribe(ctx, "guestbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A subscription outlives the read timeout.
+	time.Sleep(3 * config.RedisTimeout)
+	if _, err := s.Append(context.Background(), "guestbook", Entry{Value: "late"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case entry := <-entries:
+		if entry.Value != "late" {
+			t.Errorf("expected the appended entry, got %+v", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the subscription to still receive entries")
+	}
+
+	start := time.Now()
+	if err := config.NewPool("master", "10.255.255.1:6379").Ping(); err == nil || time.Since(start) > time.Second {
+		t.Errorf("expected dialing an unreachable server to fail quickly, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestLrange(t *testing.T) {
+	list := []string{"0", "1", "2", "3"}
+	tests := []struct {
+		start, stop int
+		expected    []string
+	}{
+		{0, -1, list},
+		{1, 2, []string{"1", "2"}},
+		{-2, -1, []string{"2", "3"}},
+		{-10, 0, []string{"0"}},
+		{2, 10, []string{"2", "3"}},
+		{3, 1, nil},
+		{0, -10, nil},
+	}
+	for _, test := range tests {
+		if got := lrange(list, test.start, test.stop); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("lrange(%d, %d): expected %q, got %q", test.start, test.stop, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// streamHeartbeat is how often an idle stream is sent a comment line, which
+// keeps proxies from timing out the connection.
+const streamHeartbeat = 15 * time.Second
+
+// newStreamHandler returns a handler that sends every entry appended to the
+// list as a server-sent event. Streams never end on their own, so they are
+// all closed once done is closed to let the server shut down.
+//
+// A reconnecting EventSource sends the ID of the last entry it saw as
+// Last-Event-ID, and the entries after it in the list are replayed. Indexes
+// would not do, since they shift when entries are trimmed, removed or
+// hidden. If the entry is gone from the list a reset event is sent instead,
+// telling the client to read the whole list again.
+func newStreamHandler(store Store, done <-chan struct{}) appHandler {
+	return func(rw http.ResponseWriter, req *http.Request) error {
+		key := mux.Vars(req)["key"]
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "streaming is not supported"}
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		entries, err := store.Subscribe(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		var missed []Entry
+		found := true
+		if lastID := req.Header.Get("Last-Event-ID"); lastID != "" {
+			list, _, err := store.Range(ctx, key, Page{})
+			if err != nil {
+				return err
+			}
+			missed, found = entriesAfter(list, lastID)
+		}
+
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("X-Accel-Buffering", "no")
+		rw.WriteHeader(http.StatusOK)
+		if !found {
+			// Browsers drop events without data.
+			fmt.Fprint(rw, "event: reset\ndata: {}\n\n")
+		}
+		for _, entry := range missed {
+			writeEvent(rw, entry)
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case entry, ok := <-entries:
+				if !ok {
+					// The store went away; the client will reconnect.
+					return nil
+				}
+				writeEvent(rw, entry)
+			case <-heartbeat.C:
+				fmt.Fprint(rw, ": heartbeat\n\n")
+			case <-done:
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// entriesAfter returns the entries of list after the one with the given ID,
+// or false if there is none.
+func entriesAfter(list []Entry, id string) ([]Entry, bool) {
+	if i := lastIndexOf(list, id); i >= 0 {
+		return list[i+1:], true
+	}
+	return nil, false
+}
+
+// writeEvent writes entry as an "entry" event whose ID is the entry's ID.
+func writeEvent(rw http.ResponseWriter, entry Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("encoding stream event failed", "error", err.Error())
+		return
+	}
+	fmt.Fprintf(rw, "id: %s\nevent: entry\ndata: %s\n\n", entry.ID, data)
+}
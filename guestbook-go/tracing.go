@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codegangsta/negroni"
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the guestbook's spans.
+const tracerName = "k8s.io/examples/guestbook-go"
+
+// SetupTracing sends traces to the configured OTLP/HTTP collector and returns
+// a function that flushes the remaining spans. Without an endpoint the global
+// tracer provider is left alone, so that spans are not recorded at all.
+func (c *Config) SetupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	if c.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	endpoint, err := url.Parse(c.OTLPEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("-otlp-endpoint %q must be an http or https URL", c.OTLPEndpoint)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = "/v1/traces"
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return nil, fmt.Errorf("-trace-sample-ratio must be between 0 and 1, got %v", c.TraceSampleRatio)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint.String()))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "guestbook"))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.TraceSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// tracingMiddleware is a mux middleware that traces every matched request
+// under its route template, continuing the trace of the caller if it sent
+// one.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		route := routeTemplate(req)
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer().Start(ctx, req.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", req.URL.Path),
+			))
+		defer span.End()
+		if span.IsRecording() {
+			span.SetAttributes(attribute.String("guestbook.request_id", requestID(req)))
+		}
+
+		nrw := negroni.NewResponseWriter(rw)
+		next.ServeHTTP(nrw, req.WithContext(ctx))
+
+		status := nrw.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// tracedConn starts a span for every command sent with Do on a connection of
+// the named pool. Commands queued with Send, such as those of a MULTI
+// transaction, are listed on the span of the Do that runs them.
+type tracedConn struct {
+	redis.Conn
+	ctx    context.Context
+	pool   string
+	queued []string
+}
+
+// traceConn returns conn with its commands traced as children of the span
+// in ctx.
+func traceConn(ctx context.Context, pool string, conn redis.Conn) redis.Conn {
+	return &tracedConn{Conn: conn, ctx: ctx, pool: pool}
+}
+
+func (c *tracedConn) Send(command string, args ...interface{}) error {
+	c.queued = append(c.queued, strings.ToUpper(command))
+	return c.Conn.Send(command, args...)
+}
+
+func (c *tracedConn) Do(command string, args ...interface{}) (interface{}, error) {
+	if command == "" {
+		// Only flushes the queued commands, which the next Do records.
+		return c.Conn.Do(command, args...)
+	}
+	operation := strings.ToUpper(command)
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", operation),
+		attribute.String("redis.pool", c.pool),
+	}
+	if len(c.queued) > 0 {
+		attrs = append(attrs, attribute.StringSlice("redis.queued", c.queued))
+		c.queued = nil
+	}
+	_, span := tracer().Start(c.ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	reply, err := c.Conn.Do(command, args...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCollector stands in for an OTLP/HTTP collector and keeps the spans it
+// is sent.
+type testCollector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *testCollector) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v1/traces" {
+		http.NotFound(rw, req)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var export coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &export); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	for _, resourceSpans := range export.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	c.mu.Unlock()
+	reply, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	rw.Header().Set("Content-Type", "application/x-protobuf")
+	rw.Write(reply)
+}
+
+// span returns the first span named name.
+func (c *testCollector) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span %q among %d", name, len(c.spans))
+	return nil
+}
+
+func spanAttribute(span *tracepb.Span, key string) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestTracing(t *testing.T) {
+	collector := &testCollector{}
+	collectorServer := httptest.NewServer(collector)
+	defer collectorServer.Close()
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	config := Config{OTLPEndpoint: collectorServer.URL, TraceSampleRatio: 1}
+	shutdown, err := config.SetupTracing(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, _ := newTestRedisStore(t)
+	server := newTestServer(t, s)
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	resp := do(t, "POST", server.URL+"/api/v1/lists/guestbook/entries", `{"value":"hello"}`, "traceparent", "00-"+traceID+"-"+parentID+"-01")
+	expectStatus(t, resp, http.StatusCreated)
+	expectStatus(t, do(t, "GET", server.URL+"/api/v1/lists/guestbook/entries", ""), http.StatusOK)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := collector.span(t, "POST /api/v1/lists/{key}/entries")
+	if hex.EncodeToString(request.TraceId) != traceID || hex.EncodeToString(request.ParentSpanId) != parentID {
+		t.Errorf("expected the request to continue the caller's trace, got trace %x parent %x", request.TraceId, request.ParentSpanId)
+	}
+	if request.Kind != tracepb.Span_SPAN_KIND_SERVER || spanAttribute(request, "http.route") != "/api/v1/lists/{key}/entries" {
+		t.Errorf("expected a server span for the route, got %v", request)
+	}
+
+	push := collector.span(t, "RPUSH")
+	if string(push.TraceId) != string(request.TraceId) || string(push.ParentSpanId) != string(request.SpanId) {
+		t.Errorf("expected RPUSH to be a child of the request span, got %v", push)
+	}
+	if pool := spanAttribute(push, "redis.pool"); pool != "master" {
+		t.Errorf("expected RPUSH on the master pool, got %q", pool)
+	}
+
+	// The read is a transaction, whose commands are listed on its EXEC.
+	exec := collector.span(t, "EXEC")
+	if pool := spanAttribute(exec, "redis.pool"); pool != "slave" {
+		t.Errorf("expected the read on the slave pool, got %q", pool)
+	}
+	var queued []string
+	for _, attr := range exec.Attributes {
+		if attr.Key == "redis.queued" {
+			for _, value := range attr.Value.GetArrayValue().GetValues() {
+				queued = append(queued, value.GetStringValue())
+			}
+		}
+	}
+	if expected := []string{"MULTI", "LLEN", "LRANGE"}; !reflect.DeepEqual(queued, expected) {
+		t.Errorf("expected the queued commands %q on EXEC, got %q", expected, queued)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	previousProvider := otel.GetTracerProvider()
+	shutdown, err := (&Config{}).SetupTracing(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() != previousProvider {
+		t.Error("expected the tracer provider to be left alone without an endpoint")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	for _, endpoint := range []string{"collector:4318", "ftp://collector"} {
+		if _, err := (&Config{OTLPEndpoint: endpoint, TraceSampleRatio: 1}).SetupTracing(context.Background()); err == nil {
+			t.Errorf("expected -otlp-endpoint %q to be rejected", endpoint)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// publicFiles are the files of the UI, built into the binary so that it
+// does not depend on the working directory.
+//
+//go:embed public
+var publicFiles embed.FS
+
+// publicDir holds the files of the UI within publicFiles.
+const publicDir = "public"
+
+// pageTemplate is the file in publicDir the pages are rendered from. All
+// other files are served as they are.
+const pageTemplate = "index.html"
+
+// defaultGuestbook is the list shown at /.
+const defaultGuestbook = "guestbook"
+
+// staticMaxAge is how long browsers may use a static file before checking
+// with its ETag whether it has changed.
+const staticMaxAge = 5 * time.Minute
+
+// themeColors are picked from for every page without -theme-color: purple,
+// blue, red, green and yellow.
+var themeColors = []string{"#549", "#18d", "#d31", "#2a4", "#db1"}
+
+var themeColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// staticFile is a file of the UI together with its ETag, a hash of the
+// content.
+type staticFile struct {
+	name    string
+	content []byte
+	etag    string
+}
+
+// userInterface serves the pages and static files of the UI.
+type userInterface struct {
+	page   *template.Template
+	static map[string]staticFile
+
+	title      string
+	themeColor string
+	hostInfo   string
+	admin      bool
+}
+
+// pageData is what a page is rendered with.
+type pageData struct {
+	// Title is the title of the document and Heading the text of its
+	// header.
+	Title   string
+	Heading string
+	// Guestbook is the list the page shows.
+	Guestbook  string
+	ThemeColor string
+	// HostInfo tells which replica served the page.
+	HostInfo string
+	// Admin links to the debug pages.
+	Admin bool
+}
+
+// newUI returns the UI for the configuration. Without a -host-info the host
+// name is shown, which is the pod name in Kubernetes.
+func (c *Config) newUI() (*userInterface, error) {
+	if c.ThemeColor != "" && !themeColorPattern.MatchString(c.ThemeColor) {
+		return nil, fmt.Errorf("invalid theme color %q, must be #rgb or #rrggbb", c.ThemeColor)
+	}
+	hostInfo := c.HostInfo
+	if hostInfo == "" {
+		hostInfo, _ = os.Hostname()
+	}
+	files, err := fs.Sub(publicFiles, publicDir)
+	if err != nil {
+		return nil, err
+	}
+	page, err := template.ParseFS(files, pageTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	ui := &userInterface{
+		page:       page,
+		static:     make(map[string]staticFile),
+		title:      c.Title,
+		themeColor: c.ThemeColor,
+		hostInfo:   hostInfo,
+		admin:      c.Admin,
+	}
+	err = fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path == pageTemplate {
+			return err
+		}
+		content, err := fs.ReadFile(files, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		ui.static[path] = staticFile{name: path, content: content, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ui, nil
+}
+
+// route adds the routes of the UI to r.
+func (u *userInterface) route(r *mux.Router) {
+	r.Path("/").Methods("GET", "HEAD").Handler(appHandler(u.PageHandler))
+	r.Path("/g/{name}").Methods("GET", "HEAD").Handler(appHandler(u.PageHandler))
+	for path, file := range u.static {
+		r.Path("/"+path).Methods("GET", "HEAD").Handler(file)
+	}
+}
+
+// PageHandler renders the UI for the guestbook named in the path, or for
+// the default guestbook at /. Pages are not cached since the theme color
+// may be picked anew for each of them.
+func (u *userInterface) PageHandler(rw http.ResponseWriter, req *http.Request) error {
+	data := pageData{
+		Title:      u.title,
+		Heading:    u.title,
+		Guestbook:  defaultGuestbook,
+		ThemeColor: u.themeColor,
+		HostInfo:   u.hostInfo,
+		Admin:      u.admin,
+	}
+	if name, ok := mux.Vars(req)["name"]; ok {
+		if err := validateKey(name); err != nil {
+			return err
+		}
+		data.Title = name + " - " + u.title
+		data.Heading = name
+		data.Guestbook = name
+	}
+	if data.ThemeColor == "" {
+		data.ThemeColor = themeColors[rand.IntN(len(themeColors))]
+	}
+
+	var buf bytes.Buffer
+	if err := u.page.Execute(&buf, data); err != nil {
+		return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "rendering the page failed", Err: err}
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-cache")
+	_, err := rw.Write(buf.Bytes())
+	return err
+}
+
+// ServeHTTP serves the file with its ETag, answering a request with a
+// matching If-None-Match with 304 Not Modified.
+func (f staticFile) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("ETag", f.etag)
+	rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(staticMaxAge.Seconds())))
+	http.ServeContent(rw, req, f.name, time.Time{}, bytes.NewReader(f.content))
+}